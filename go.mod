@@ -3,6 +3,7 @@ module zori
 go 1.24.4
 
 require (
+	github.com/Cleverse/go-utilities/nullable v0.0.0-20250808171844-1347aec4138e
 	github.com/ClickHouse/clickhouse-go/v2 v2.40.3
 	github.com/caarlos0/env/v11 v11.3.1
 	github.com/go-playground/validator/v10 v10.27.0
@@ -10,6 +11,7 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/joho/godotenv v1.5.1
 	github.com/labstack/echo/v4 v4.13.4
+	github.com/medama-io/go-useragent v1.2.2
 	github.com/nats-io/nats.go v1.46.1
 	github.com/oschwald/maxminddb-golang/v2 v2.0.0-beta.10
 	github.com/speps/go-hashids/v2 v2.0.1
@@ -25,11 +27,11 @@ require (
 	github.com/valyala/fasthttp v1.66.0
 	go.uber.org/fx v1.24.0
 	golang.org/x/crypto v0.42.0
+	golang.org/x/net v0.44.0
 )
 
 require (
 	github.com/Cleverse/go-utilities/errors v0.0.0-20231113142714-2364608744a9 // indirect
-	github.com/Cleverse/go-utilities/nullable v0.0.0-20250808171844-1347aec4138e // indirect
 	github.com/ClickHouse/ch-go v0.69.0 // indirect
 	github.com/KyleBanks/depth v1.2.1 // indirect
 	github.com/andybalholm/brotli v1.2.0 // indirect
@@ -59,7 +61,6 @@ require (
 	github.com/leodido/go-urn v1.4.0 // indirect
 	github.com/mattn/go-colorable v0.1.14 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
-	github.com/medama-io/go-useragent v1.2.2 // indirect
 	github.com/nats-io/nkeys v0.4.11 // indirect
 	github.com/nats-io/nuid v1.0.1 // indirect
 	github.com/paulmach/orb v0.12.0 // indirect
@@ -82,7 +83,6 @@ require (
 	go.yaml.in/yaml/v3 v3.0.4 // indirect
 	golang.org/x/exp v0.0.0-20230213192124-5e25df0256eb // indirect
 	golang.org/x/mod v0.28.0 // indirect
-	golang.org/x/net v0.44.0 // indirect
 	golang.org/x/sync v0.17.0 // indirect
 	golang.org/x/sys v0.37.0 // indirect
 	golang.org/x/text v0.29.0 // indirect
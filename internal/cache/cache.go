@@ -1,25 +1,122 @@
 package cache
 
-import "time"
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"zori/internal/config"
+)
 
-type CacheService struct{}
+// ErrNotFound is returned by Get for keys which are missing or expired
+var ErrNotFound = errors.New("cache: key not found")
 
-func NewCacheService() *CacheService {
-	return &CacheService{}
+// Cache stores values for a limited time. Implementations are safe for concurrent use, the interface fits
+// shared stores such as Redis as well as the in-process LRU.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores the value for ttl, a ttl of zero keeps the value until it is evicted or deleted
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
 }
 
-func (s *CacheService) Get(key string) (string, error) {
-	return "", nil
+// NewCache returns the cache of the service, an in-process LRU holding at most CacheMaxEntries values
+func NewCache(cfg *config.Config) Cache {
+	return NewLRU(cfg.CacheMaxEntries)
 }
 
-func (s *CacheService) Set(key string, value string) error {
-	return nil
+// LRU is an in-process Cache evicting the least recently used value once it holds capacity values
+type LRU struct {
+	capacity int
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// order holds the entries from the most to the least recently used
+	order *list.List
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func NewLRU(capacity int) *LRU {
+	return &LRU{
+		capacity: max(capacity, 1),
+		now:      time.Now,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
 }
 
-func (s *CacheService) Delete(key string) error {
+func (c *LRU) Get(_ context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	entry := element.Value.(*lruEntry)
+	if !entry.expiresAt.IsZero() && !c.now().Before(entry.expiresAt) {
+		c.remove(element)
+		return nil, ErrNotFound
+	}
+
+	c.order.MoveToFront(element)
+	return entry.value, nil
+}
+
+func (c *LRU) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = c.now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+
 	return nil
 }
 
-func (s *CacheService) Expire(key string, duration time.Duration) error {
+func (c *LRU) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+
 	return nil
 }
+
+// Len returns the number of values held, expired values included until they are evicted or read
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *LRU) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*lruEntry).key)
+}
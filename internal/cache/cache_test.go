@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLRUEviction(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+
+	c.Set(ctx, "a", []byte("1"), 0)
+	c.Set(ctx, "b", []byte("2"), 0)
+
+	// reading a makes b the least recently used value
+	if _, err := c.Get(ctx, "a"); err != nil {
+		t.Fatalf("Expected a to be cached, got %v", err)
+	}
+
+	c.Set(ctx, "c", []byte("3"), 0)
+
+	if _, err := c.Get(ctx, "b"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected b to be evicted, got %v", err)
+	}
+
+	for _, key := range []string{"a", "c"} {
+		if _, err := c.Get(ctx, key); err != nil {
+			t.Errorf("Expected %s to be cached, got %v", key, err)
+		}
+	}
+
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 values, got %d", c.Len())
+	}
+}
+
+func TestLRUExpiration(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	c := NewLRU(10)
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "short", []byte("1"), time.Minute)
+	c.Set(ctx, "forever", []byte("2"), 0)
+
+	now = now.Add(59 * time.Second)
+	if value, err := c.Get(ctx, "short"); err != nil || string(value) != "1" {
+		t.Fatalf("Expected short to be cached, got %q, %v", value, err)
+	}
+
+	now = now.Add(time.Second)
+	if _, err := c.Get(ctx, "short"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected short to be expired, got %v", err)
+	}
+
+	if _, err := c.Get(ctx, "forever"); err != nil {
+		t.Errorf("Expected forever to be cached, got %v", err)
+	}
+
+	if c.Len() != 1 {
+		t.Errorf("Expected expired values to be removed once read, got %d values", c.Len())
+	}
+}
+
+func TestLRUSetAndDelete(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+
+	c.Set(ctx, "a", []byte("1"), 0)
+	c.Set(ctx, "a", []byte("2"), 0)
+
+	if value, _ := c.Get(ctx, "a"); string(value) != "2" {
+		t.Errorf("Expected the value to be replaced, got %q", value)
+	}
+
+	c.Delete(ctx, "a")
+	if _, err := c.Get(ctx, "a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a to be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+// Package clientip resolves the IP address of the client of a request sent through reverse proxies.
+//
+// Headers naming the client, cf-connecting-ip and X-Forwarded-For, can be set by anyone, so they are only read when
+// the request comes from a trusted proxy. X-Forwarded-For is read from the right, every trusted proxy appends the
+// address it received the request from, and the right-most address which is not a trusted proxy is the client.
+package clientip
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Resolver resolves client IPs behind the trusted proxies
+type Resolver struct {
+	trustedProxies []netip.Prefix
+}
+
+// New returns a resolver trusting the proxies in the given CIDR ranges, single addresses are accepted as well.
+// Without trusted proxies the headers are never read and the client is the remote address of the connection.
+func New(trustedProxies []string) (*Resolver, error) {
+	resolver := &Resolver{}
+	for _, proxy := range trustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(proxy)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+			}
+			prefix = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+		}
+		resolver.trustedProxies = append(resolver.trustedProxies, prefix.Masked())
+	}
+
+	return resolver, nil
+}
+
+// ClientIP returns the IP of the client of a request from remoteAddr carrying the cf-connecting-ip and
+// X-Forwarded-For headers, either may be empty. IPv4 addresses mapped to IPv6 are returned as IPv4.
+func (r *Resolver) ClientIP(remoteAddr netip.Addr, cloudflareIP string, forwardedFor string) netip.Addr {
+	client := remoteAddr.Unmap()
+	if !r.Trusted(client) {
+		return client
+	}
+
+	if addr, ok := parseHop(cloudflareIP); ok {
+		return addr
+	}
+
+	hops := strings.Split(forwardedFor, ",")
+	for idx := len(hops) - 1; idx >= 0; idx-- {
+		addr, ok := parseHop(hops[idx])
+		if !ok {
+			// hops left of an invalid one cannot be attributed, the last valid hop is the best guess
+			break
+		}
+
+		client = addr
+		if !r.Trusted(client) {
+			break
+		}
+	}
+
+	return client
+}
+
+// HasTrustedProxies reports whether any proxy is trusted, proxy headers are never read otherwise
+func (r *Resolver) HasTrustedProxies() bool {
+	return len(r.trustedProxies) > 0
+}
+
+// Trusted reports whether the address belongs to a trusted proxy
+func (r *Resolver) Trusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range r.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHop parses an address of a proxy header, some proxies add the port of the client as well
+func parseHop(hop string) (netip.Addr, bool) {
+	hop = strings.TrimSpace(hop)
+	if hop == "" {
+		return netip.Addr{}, false
+	}
+
+	if addr, err := netip.ParseAddr(hop); err == nil {
+		return addr.Unmap(), true
+	}
+
+	if addrPort, err := netip.ParseAddrPort(hop); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+
+	return netip.Addr{}, false
+}
@@ -0,0 +1,72 @@
+package clientip
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestResolverClientIP(t *testing.T) {
+	resolver, err := New([]string{"10.0.0.0/8", "2001:db8::1"})
+	if err != nil {
+		t.Fatalf("Failed to parse trusted proxies: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		cloudflareIP string
+		forwardedFor string
+		want         string
+	}{
+		{"direct client", "198.51.100.7", "", "", "198.51.100.7"},
+		{"headers of untrusted remote", "198.51.100.7", "203.0.113.1", "203.0.113.2", "198.51.100.7"},
+		{"cloudflare header of trusted proxy", "10.0.0.1", "203.0.113.1", "203.0.113.2", "203.0.113.1"},
+		{"invalid cloudflare header", "10.0.0.1", "unknown", "203.0.113.2", "203.0.113.2"},
+		{"right-most untrusted hop", "10.0.0.1", "", "192.0.2.9, 203.0.113.2, 10.0.0.2", "203.0.113.2"},
+		{"spoofed left-most hop", "10.0.0.1", "", "1.1.1.1,203.0.113.2", "203.0.113.2"},
+		{"only trusted hops", "10.0.0.1", "", "10.0.0.3, 10.0.0.2", "10.0.0.3"},
+		{"invalid hop", "10.0.0.1", "", "203.0.113.2, garbage, 10.0.0.2", "10.0.0.2"},
+		{"hop with port", "10.0.0.1", "", "203.0.113.2:51234", "203.0.113.2"},
+		{"trusted proxy without headers", "10.0.0.1", "", "", "10.0.0.1"},
+		{"trusted single IPv6 proxy", "2001:db8::1", "", "2001:db8::42", "2001:db8::42"},
+		{"mapped IPv4 remote", "::ffff:198.51.100.7", "", "", "198.51.100.7"},
+		{"mapped IPv4 hop", "10.0.0.1", "", "::ffff:203.0.113.2", "203.0.113.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolver.ClientIP(netip.MustParseAddr(tt.remoteAddr), tt.cloudflareIP, tt.forwardedFor)
+			if got.String() != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewInvalidProxy(t *testing.T) {
+	if _, err := New([]string{"10.0.0.0/8", "not a proxy"}); err == nil {
+		t.Error("Expected an invalid proxy to fail")
+	}
+}
+
+func TestResolverTrusted(t *testing.T) {
+	resolver, err := New([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("Failed to parse trusted proxies: %v", err)
+	}
+
+	if !resolver.HasTrustedProxies() || !resolver.Trusted(netip.MustParseAddr("::ffff:10.1.2.3")) {
+		t.Error("Expected an address of the trusted range to be trusted")
+	}
+	if resolver.Trusted(netip.MustParseAddr("198.51.100.7")) {
+		t.Error("Expected an address outside of the trusted range not to be trusted")
+	}
+
+	resolver, err = New(nil)
+	if err != nil {
+		t.Fatalf("Failed to create resolver: %v", err)
+	}
+	if resolver.HasTrustedProxies() {
+		t.Error("Expected a resolver without proxies not to trust any")
+	}
+}
@@ -20,6 +20,44 @@ type Config struct {
 
 	NatsCredentialsContent string `env:"NATS_CREDENTIALS_CONTENT,required"`
 	NatsStreamURL          string `env:"NATS_STREAM_URL,required"`
+	NatsStreamMaxBytes     int64  `env:"NATS_STREAM_MAX_BYTES" envDefault:"104857600"`
+
+	// Ingestion publishing Configuration
+	NatsPublishMaxPending int           `env:"NATS_PUBLISH_MAX_PENDING" envDefault:"4096"`
+	NatsPublishStallWait  time.Duration `env:"NATS_PUBLISH_STALL_WAIT" envDefault:"200ms"`
+	NatsPublishAckTimeout time.Duration `env:"NATS_PUBLISH_ACK_TIMEOUT" envDefault:"5s"`
+
+	// VisitorCookieDomain is the domain of the visitor_id cookie, the cookie is bound to the ingestion host when empty
+	VisitorCookieDomain string `env:"VISITOR_COOKIE_DOMAIN"`
+	// TrustedProxies are the CIDR ranges of the reverse proxies in front of ingestion, client IPs are only read from
+	// the cf-connecting-ip and X-Forwarded-For headers of requests coming from them
+	TrustedProxies []string `env:"TRUSTED_PROXIES" envSeparator:","`
+
+	// Ingestion limits Configuration, rates are events per second and a rate of 0 disables the limit
+	IngestProjectRate    float64       `env:"INGEST_PROJECT_RATE" envDefault:"200"`
+	IngestProjectBurst   int           `env:"INGEST_PROJECT_BURST" envDefault:"1000"`
+	IngestIPRate         float64       `env:"INGEST_IP_RATE" envDefault:"20"`
+	IngestIPBurst        int           `env:"INGEST_IP_BURST" envDefault:"200"`
+	UsageFlushInterval   time.Duration `env:"USAGE_FLUSH_INTERVAL" envDefault:"10s"`
+	UsageRefreshInterval time.Duration `env:"USAGE_REFRESH_INTERVAL" envDefault:"1m"`
+
+	// Cache Configuration
+	CacheMaxEntries         int           `env:"CACHE_MAX_ENTRIES" envDefault:"10000"`
+	ProjectCacheTTL         time.Duration `env:"PROJECT_CACHE_TTL" envDefault:"5m"`
+	ProjectCacheNegativeTTL time.Duration `env:"PROJECT_CACHE_NEGATIVE_TTL" envDefault:"30s"`
+
+	// Event processing Configuration
+	EventsMaxDeliver    int           `env:"EVENTS_MAX_DELIVER" envDefault:"5"`
+	EventsBatchSize     int           `env:"EVENTS_BATCH_SIZE" envDefault:"500"`
+	EventsFlushInterval time.Duration `env:"EVENTS_FLUSH_INTERVAL" envDefault:"5s"`
+	// BotIPRangesPath is a file of datacenter IP ranges, one CIDR per line, replacing the embedded ranges events
+	// are detected as bots from
+	BotIPRangesPath string `env:"BOT_IP_RANGES_PATH"`
+	// GeoIPPath is the MaxMind city database, GeoIPASNPath the optional ASN database
+	GeoIPPath    string `env:"GEOIP_PATH" envDefault:"./ipdb.mmdb"`
+	GeoIPASNPath string `env:"GEOIP_ASN_PATH"`
+	// ChannelSourcesPath is a JSON source list replacing the embedded one traffic channels are classified with
+	ChannelSourcesPath string `env:"CHANNEL_SOURCES_PATH"`
 
 	// Bcrypt Configuration
 	BcryptCost int `env:"BCRYPT_COST" envDefault:"12"`
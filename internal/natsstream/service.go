@@ -2,14 +2,36 @@ package natsstream
 
 import (
 	"errors"
+	"fmt"
+	"slices"
 	"zori/internal/config"
 
 	"github.com/nats-io/nats.go"
 )
 
+const (
+	RawEventsStream  = "events:raw"
+	RawEventsSubject = "events:raw"
+
+	DeadLetterEventsStream  = "events:dead"
+	DeadLetterEventsSubject = "events:dead"
+
+	// EnrichedEventsSubject prefixes the core NATS subjects enriched events are published on as they are stored,
+	// followed by the organization and project of the event. Nothing is persisted, only live subscribers receive them.
+	EnrichedEventsSubject = "events:enriched"
+)
+
+// EnrichedEventsProjectSubject returns the subject the enriched events of a project are published on,
+// a projectID of "*" matches every project of the organization.
+func EnrichedEventsProjectSubject(organizationID string, projectID string) string {
+	return EnrichedEventsSubject + "." + organizationID + "." + projectID
+}
+
 type Stream struct {
 	nc *nats.Conn
 	js nats.JetStreamContext
+
+	maxBytes int64
 }
 
 func NewStream(conf *config.Config) *Stream {
@@ -29,33 +51,78 @@ func NewStream(conf *config.Config) *Stream {
 		}
 	}
 
-	js, err := nc.JetStream()
+	// Max pending bounds how many published messages may wait for an ack at once,
+	// once reached async publishing stalls which is how ingestion applies backpressure.
+	js, err := nc.JetStream(nats.PublishAsyncMaxPending(conf.NatsPublishMaxPending))
 	if err != nil {
 		panic(err)
 	}
 
 	return &Stream{
-		nc: nc,
-		js: js,
+		nc:       nc,
+		js:       js,
+		maxBytes: conf.NatsStreamMaxBytes,
 	}
 }
 
+// UpsertJetStream creates or updates the stream. The stream is a work queue that rejects new messages once full,
+// so publishers are told about it instead of older events being silently discarded.
 func (s *Stream) UpsertJetStream(name string, sourceSubject string) error {
-	streamInfo, err := s.js.StreamInfo(name)
+	return s.upsertStream(&nats.StreamConfig{
+		Name:      name,
+		Subjects:  []string{sourceSubject},
+		MaxBytes:  s.maxBytes,
+		Retention: nats.WorkQueuePolicy,
+		Discard:   nats.DiscardNew,
+	})
+}
+
+// UpsertDeadLetterStream creates or updates the dead-letter stream, messages are kept
+// until they are re-driven or deleted so they can be inspected any number of times.
+func (s *Stream) UpsertDeadLetterStream(name string, sourceSubject string) error {
+	return s.upsertStream(&nats.StreamConfig{
+		Name:      name,
+		Subjects:  []string{sourceSubject},
+		MaxBytes:  s.maxBytes,
+		Retention: nats.LimitsPolicy,
+		Discard:   nats.DiscardNew,
+	})
+}
+
+// upsertStream creates the stream, or updates an existing one whose subjects, size or discard policy differ so
+// config changes apply to running deployments. The retention policy of a stream cannot be changed in place, a stream
+// created with another one has to be drained and deleted (nats stream rm <name>) before it is created again.
+func (s *Stream) upsertStream(streamConfig *nats.StreamConfig) error {
+	streamInfo, err := s.js.StreamInfo(streamConfig.Name)
 	if err != nil && !errors.Is(err, nats.ErrStreamNotFound) {
 		return err
 	}
 	if streamInfo == nil {
-		streamInfo, err = s.js.AddStream(&nats.StreamConfig{
-			Name:     name,
-			Subjects: []string{sourceSubject},
-			MaxBytes: 100000,
-		})
-		if err != nil {
-			return err
-		}
+		_, err = s.js.AddStream(streamConfig)
+		return err
 	}
 
+	current := streamInfo.Config
+	if current.Retention != streamConfig.Retention {
+		return fmt.Errorf(
+			"stream %s has %s retention but %s is required, retention cannot be changed in place: "+
+				"stop publishing to the stream, wait for its consumers to drain it and delete it with `nats stream rm %s`, "+
+				"it is created again on the next start",
+			streamConfig.Name, current.Retention, streamConfig.Retention, streamConfig.Name)
+	}
+
+	if slices.Equal(current.Subjects, streamConfig.Subjects) &&
+		current.MaxBytes == streamConfig.MaxBytes &&
+		current.Discard == streamConfig.Discard {
+		return nil
+	}
+
+	// other settings of the stream, such as its replicas, are kept as they are
+	current.Subjects = streamConfig.Subjects
+	current.MaxBytes = streamConfig.MaxBytes
+	current.Discard = streamConfig.Discard
+
+	_, err = s.js.UpdateStream(&current)
 	return err
 }
 
@@ -0,0 +1,121 @@
+// Package origins matches the origins of requests against the allowed origins of a project.
+//
+// Allowed origins are host patterns such as example.com, app.example.com:8080 or *.example.com. A wildcard
+// matches every subdomain of the domain but not the domain itself, and a pattern without a port matches any port.
+package origins
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Limits on the allowed origins of a project
+const (
+	MaxPatterns      = 50
+	MaxPatternLength = 255
+)
+
+const wildcardPrefix = "*."
+
+// Normalize validates the pattern and returns it in its canonical form, lower case and without scheme, path or
+// trailing dot, so https://Example.com/ becomes example.com
+func Normalize(pattern string) (string, error) {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if _, rest, ok := strings.Cut(pattern, "://"); ok {
+		pattern = rest
+	}
+	pattern, _, _ = strings.Cut(pattern, "/")
+
+	host, port := splitHostPort(pattern)
+	host = strings.TrimSuffix(host, ".")
+
+	domain := strings.TrimPrefix(host, wildcardPrefix)
+	if domain == "" || len(pattern) > MaxPatternLength {
+		return "", errors.New("must be a host such as example.com or *.example.com")
+	}
+
+	for _, c := range domain {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '.' {
+			return "", errors.New("must be a host such as example.com or *.example.com, wildcards may only prefix the host")
+		}
+	}
+
+	if port != "" {
+		for _, c := range port {
+			if c < '0' || c > '9' {
+				return "", errors.New("port must be a number")
+			}
+		}
+		return host + ":" + port, nil
+	}
+
+	return host, nil
+}
+
+// NormalizeAll normalizes every pattern, dropping duplicates
+func NormalizeAll(patterns []string) ([]string, error) {
+	if len(patterns) > MaxPatterns {
+		return nil, fmt.Errorf("may contain at most %d origins", MaxPatterns)
+	}
+
+	normalized := make([]string, 0, len(patterns))
+	seen := make(map[string]bool, len(patterns))
+	for _, pattern := range patterns {
+		origin, err := Normalize(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pattern, err)
+		}
+
+		if !seen[origin] {
+			seen[origin] = true
+			normalized = append(normalized, origin)
+		}
+	}
+
+	return normalized, nil
+}
+
+// Parse returns the host and port of an Origin or Referer header value, ok is false when it has no host
+func Parse(origin string) (host string, port string, ok bool) {
+	parsedOrigin, err := url.Parse(strings.TrimSpace(origin))
+	if err != nil || parsedOrigin.Hostname() == "" {
+		return "", "", false
+	}
+
+	return strings.TrimSuffix(strings.ToLower(parsedOrigin.Hostname()), "."), parsedOrigin.Port(), true
+}
+
+// Match reports whether the host and port match one of the normalized patterns
+func Match(patterns []string, host string, port string) bool {
+	for _, pattern := range patterns {
+		patternHost, patternPort := splitHostPort(pattern)
+		if patternPort != "" && patternPort != port {
+			continue
+		}
+
+		if domain, ok := strings.CutPrefix(patternHost, wildcardPrefix); ok {
+			if strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+			continue
+		}
+
+		if host == patternHost {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsLocalhost reports whether the host is the local machine
+func IsLocalhost(host string) bool {
+	return host == "localhost" || strings.HasSuffix(host, ".localhost") || host == "127.0.0.1" || host == "::1"
+}
+
+func splitHostPort(pattern string) (string, string) {
+	host, port, _ := strings.Cut(pattern, ":")
+	return host, port
+}
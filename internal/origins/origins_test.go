@@ -0,0 +1,86 @@
+package origins
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		expected  string
+		expectErr bool
+	}{
+		{"example.com", "example.com", false},
+		{" https://Example.com/pricing ", "example.com", false},
+		{"*.example.com.", "*.example.com", false},
+		{"localhost:3000", "localhost:3000", false},
+		{"", "", true},
+		{"*.", "", true},
+		{"app.*.example.com", "", true},
+		{"example.com:http", "", true},
+		{"exa mple.com", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			normalized, err := Normalize(test.pattern)
+			if test.expectErr {
+				if err == nil {
+					t.Fatalf("Expected error, got %q", normalized)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if normalized != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, normalized)
+			}
+		})
+	}
+}
+
+func TestNormalizeAll(t *testing.T) {
+	normalized, err := NormalizeAll([]string{"example.com", "https://example.com", "*.example.com"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []string{"example.com", "*.example.com"}
+	if !reflect.DeepEqual(normalized, expected) {
+		t.Errorf("Expected %v, got %v", expected, normalized)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	patterns := []string{"example.com", "*.example.org", "localhost:3000"}
+
+	tests := []struct {
+		origin   string
+		expected bool
+	}{
+		{"https://example.com", true},
+		{"https://example.com:8443", true},
+		{"https://EXAMPLE.com.", true},
+		{"https://www.example.com", false},
+		{"https://app.example.org", true},
+		{"https://eu.app.example.org/pricing?plan=pro", true},
+		{"https://example.org", false},
+		{"https://evilexample.org", false},
+		{"https://example.org.evil.com", false},
+		{"http://localhost:3000", true},
+		{"http://localhost:8080", false},
+		{"null", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.origin, func(t *testing.T) {
+			host, port, ok := Parse(test.origin)
+			if got := ok && Match(patterns, host, port); got != test.expected {
+				t.Errorf("Expected match %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+// Package ratelimit implements token bucket rate limiting keyed by arbitrary strings such as tokens or IP addresses.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often buckets which refilled completely are dropped, a full bucket behaves like a new one
+const sweepInterval = time.Minute
+
+// Limiter holds a token bucket per key. Buckets refill at rate tokens per second up to burst tokens.
+type Limiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens   float64
+	updateAt time.Time
+}
+
+// New returns a limiter allowing rate events per second with bursts of up to burst events.
+// A rate of zero or less disables limiting.
+func New(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(max(burst, 1)),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes n tokens from the bucket of the key. When the bucket holds fewer tokens nothing is taken and
+// Allow returns false along with the time until enough tokens are available. Requests for more than burst
+// tokens are never allowed.
+func (l *Limiter) Allow(key string, n int) (bool, time.Duration) {
+	if l.rate <= 0 {
+		return true, 0
+	}
+
+	now := l.now()
+	tokens := float64(n)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, updateAt: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.updateAt).Seconds()*l.rate)
+	b.updateAt = now
+
+	if tokens > l.burst {
+		return false, time.Duration(l.burst / l.rate * float64(time.Second))
+	}
+
+	if b.tokens < tokens {
+		return false, time.Duration((tokens - b.tokens) / l.rate * float64(time.Second))
+	}
+
+	b.tokens -= tokens
+	return true, 0
+}
+
+// sweep drops the buckets which refilled since their last use
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.updateAt).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	l := New(10, 20)
+	l.now = func() time.Time { return now }
+
+	if ok, _ := l.Allow("a", 20); !ok {
+		t.Fatal("Expected a full burst to be allowed")
+	}
+
+	ok, retryAfter := l.Allow("a", 5)
+	if ok {
+		t.Fatal("Expected an empty bucket to be limited")
+	}
+	if retryAfter != 500*time.Millisecond {
+		t.Errorf("Expected to retry after 500ms, got %s", retryAfter)
+	}
+
+	if ok, _ := l.Allow("b", 1); !ok {
+		t.Error("Expected keys to have buckets of their own")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.Allow("a", 5); !ok {
+		t.Error("Expected the bucket to refill over time")
+	}
+
+	if ok, _ := l.Allow("a", 21); ok {
+		t.Error("Expected requests larger than the burst to be limited")
+	}
+}
+
+func TestLimiterSweep(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	l := New(1, 10)
+	l.now = func() time.Time { return now }
+
+	l.Allow("idle", 5)
+	now = now.Add(2 * time.Minute)
+	l.Allow("busy", 1)
+
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("Expected the refilled bucket to be dropped")
+	}
+	if _, ok := l.buckets["busy"]; !ok {
+		t.Error("Expected the used bucket to be kept")
+	}
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	l := New(0, 1)
+
+	if ok, _ := l.Allow("a", 1000); !ok {
+		t.Error("Expected a limiter without rate to allow everything")
+	}
+}
@@ -11,6 +11,10 @@ import (
 
 type HandlerFunc[T any] func(*ctx.Ctx) (T, error)
 
+// StreamHandlerFunc writes the response itself, it is used by handlers streaming their response such as server-sent events.
+// Errors are only written when the handler has not started its response yet.
+type StreamHandlerFunc func(*ctx.Ctx) error
+
 type Server struct {
 	Echo *echo.Echo
 }
@@ -116,6 +120,26 @@ func GroupPATCH[T any](g *Group, path string, handler HandlerFunc[T]) {
 	g.echo.PATCH(path, wrapHandler(g.server, handler))
 }
 
+func GroupStream(g *Group, path string, handler StreamHandlerFunc) {
+	g.echo.GET(path, wrapStreamHandler(g.server, handler))
+}
+
+func wrapStreamHandler(s *Server, handler StreamHandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		appctx, ok := c.Get("ctx").(*ctx.Ctx)
+		if !ok {
+			appctx = ctx.NewCtx(c)
+			c.Set("ctx", appctx)
+		}
+
+		if err := handler(appctx); err != nil && !c.Response().Committed {
+			return s.handleError(c, err)
+		}
+
+		return nil
+	}
+}
+
 func (g *Group) Use(middleware ...echo.MiddlewareFunc) {
 	g.echo.Use(middleware...)
 }
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE events ADD COLUMN IF NOT EXISTS custom_properties_map Map(String, String) DEFAULT map();
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE events ADD INDEX IF NOT EXISTS idx_custom_properties_keys mapKeys(custom_properties_map) TYPE bloom_filter GRANULARITY 1;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE events ADD INDEX IF NOT EXISTS idx_custom_properties_values mapValues(custom_properties_map) TYPE bloom_filter GRANULARITY 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE events DROP INDEX IF EXISTS idx_custom_properties_values;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE events DROP INDEX IF EXISTS idx_custom_properties_keys;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE events DROP COLUMN IF EXISTS custom_properties_map;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE events ADD COLUMN IF NOT EXISTS viewport_width Nullable(UInt16);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE events ADD COLUMN IF NOT EXISTS viewport_height Nullable(UInt16);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE events DROP COLUMN IF EXISTS viewport_height;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE events DROP COLUMN IF EXISTS viewport_width;
+-- +goose StatementEnd
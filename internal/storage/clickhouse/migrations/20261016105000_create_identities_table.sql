@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS identities (
+    organization_id UUID,
+    project_id UUID,
+    visitor_id String,
+    user_id String,
+    -- JSON encoded traits of identify events, empty for aliases
+    traits String,
+    identified_at DateTime64(3, 'UTC'),
+    server_timestamp_utc DateTime64(3, 'UTC') DEFAULT now64(3, 'UTC')
+) ENGINE = ReplacingMergeTree(identified_at)
+ORDER BY (organization_id, project_id, visitor_id);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS idx_user_id ON identities (user_id) TYPE bloom_filter GRANULARITY 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS identities;
+-- +goose StatementEnd
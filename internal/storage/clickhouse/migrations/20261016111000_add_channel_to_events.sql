@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE events ADD COLUMN IF NOT EXISTS channel LowCardinality(String) DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE events ADD COLUMN IF NOT EXISTS source_name LowCardinality(String) DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE events DROP COLUMN IF EXISTS source_name;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE events DROP COLUMN IF EXISTS channel;
+-- +goose StatementEnd
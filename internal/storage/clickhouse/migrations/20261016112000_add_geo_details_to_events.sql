@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS location_region_iso LowCardinality(Nullable(String)),
+    ADD COLUMN IF NOT EXISTS location_region Nullable(String),
+    ADD COLUMN IF NOT EXISTS location_postal_code Nullable(String),
+    ADD COLUMN IF NOT EXISTS location_latitude Nullable(Float32),
+    ADD COLUMN IF NOT EXISTS location_longitude Nullable(Float32),
+    ADD COLUMN IF NOT EXISTS location_timezone LowCardinality(Nullable(String)),
+    ADD COLUMN IF NOT EXISTS location_city_names Map(String, String),
+    ADD COLUMN IF NOT EXISTS location_region_names Map(String, String),
+    ADD COLUMN IF NOT EXISTS asn Nullable(UInt32),
+    ADD COLUMN IF NOT EXISTS as_organization Nullable(String);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE events
+    DROP COLUMN IF EXISTS as_organization,
+    DROP COLUMN IF EXISTS asn,
+    DROP COLUMN IF EXISTS location_region_names,
+    DROP COLUMN IF EXISTS location_city_names,
+    DROP COLUMN IF EXISTS location_timezone,
+    DROP COLUMN IF EXISTS location_longitude,
+    DROP COLUMN IF EXISTS location_latitude,
+    DROP COLUMN IF EXISTS location_postal_code,
+    DROP COLUMN IF EXISTS location_region,
+    DROP COLUMN IF EXISTS location_region_iso;
+-- +goose StatementEnd
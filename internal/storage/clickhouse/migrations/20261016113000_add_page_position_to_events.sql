@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE events ADD COLUMN IF NOT EXISTS page_position_x Nullable(Float64);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE events ADD COLUMN IF NOT EXISTS page_position_y Nullable(Float64);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE events DROP COLUMN IF EXISTS page_position_y;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE events DROP COLUMN IF EXISTS page_position_x;
+-- +goose StatementEnd
@@ -0,0 +1,83 @@
+-- +goose Up
+-- Traits move to a table of their own keyed by user, so aliases and identifies without traits no longer replace
+-- the identity carrying them when rows of a visitor are merged. Both tables are versioned by the time the server
+-- stored the row instead of the client timestamp.
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS user_traits (
+    organization_id UUID,
+    project_id UUID,
+    user_id String,
+    -- JSON encoded traits of the identify event
+    traits String,
+    identified_at DateTime64(3, 'UTC'),
+    server_timestamp_utc DateTime64(3, 'UTC') DEFAULT now64(3, 'UTC')
+) ENGINE = ReplacingMergeTree(server_timestamp_utc)
+ORDER BY (organization_id, project_id, user_id);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO user_traits (organization_id, project_id, user_id, traits, identified_at, server_timestamp_utc)
+SELECT organization_id, project_id, user_id, traits, identified_at, server_timestamp_utc
+FROM identities
+WHERE traits != '';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS identities_by_server_time (
+    organization_id UUID,
+    project_id UUID,
+    visitor_id String,
+    user_id String,
+    identified_at DateTime64(3, 'UTC'),
+    server_timestamp_utc DateTime64(3, 'UTC') DEFAULT now64(3, 'UTC'),
+    INDEX idx_user_id user_id TYPE bloom_filter GRANULARITY 1
+) ENGINE = ReplacingMergeTree(server_timestamp_utc)
+ORDER BY (organization_id, project_id, visitor_id);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO identities_by_server_time (organization_id, project_id, visitor_id, user_id, identified_at, server_timestamp_utc)
+SELECT organization_id, project_id, visitor_id, user_id, identified_at, server_timestamp_utc
+FROM identities;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+EXCHANGE TABLES identities AND identities_by_server_time;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS identities_by_server_time;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS identities_by_client_time (
+    organization_id UUID,
+    project_id UUID,
+    visitor_id String,
+    user_id String,
+    traits String,
+    identified_at DateTime64(3, 'UTC'),
+    server_timestamp_utc DateTime64(3, 'UTC') DEFAULT now64(3, 'UTC'),
+    INDEX idx_user_id user_id TYPE bloom_filter GRANULARITY 1
+) ENGINE = ReplacingMergeTree(identified_at)
+ORDER BY (organization_id, project_id, visitor_id);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO identities_by_client_time (organization_id, project_id, visitor_id, user_id, traits, identified_at, server_timestamp_utc)
+SELECT organization_id, project_id, visitor_id, user_id, '', identified_at, server_timestamp_utc
+FROM identities;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+EXCHANGE TABLES identities AND identities_by_client_time;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS identities_by_client_time;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_traits;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS bot_visitors (
+    organization_id UUID,
+    project_id UUID,
+    visitor_id String,
+    -- when the visitor was found sending too many events, their earlier events are left out of reports as well
+    detected_at DateTime64(3, 'UTC')
+) ENGINE = ReplacingMergeTree(detected_at)
+ORDER BY (organization_id, project_id, visitor_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS bot_visitors;
+-- +goose StatementEnd
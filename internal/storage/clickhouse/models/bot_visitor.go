@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/go-clickhouse/ch"
+)
+
+// BotVisitor is a visitor found sending events faster than a person, all events of the visitor are left out of reports
+type BotVisitor struct {
+	ch.CHModel `ch:"bot_visitors,engine:ReplacingMergeTree(detected_at),order:organization_id,order:project_id,order:visitor_id"`
+
+	OrganizationID string    `ch:"organization_id"`
+	ProjectID      string    `ch:"project_id"`
+	VisitorID      string    `ch:"visitor_id"`
+	DetectedAt     time.Time `ch:"detected_at"`
+}
@@ -28,29 +28,49 @@ type Event struct {
 	// Processed Request Medata
 	ReferrerDomain *string `ch:"referrer_domain"`
 	ReferrerPath   *string `ch:"referrer_path"`
+	Channel        string  `ch:"channel"`
+	SourceName     string  `ch:"source_name"`
 
 	BrowserName *string `ch:"browser_name"`
 	OsName      *string `ch:"os_name"`
 	DeviceType  *string `ch:"device_type"`
+	IsBot       bool    `ch:"is_bot"`
 
 	// Interaction data
 	ClickOn        *string  `ch:"click_on"`
 	ClickPositionX *float64 `ch:"click_position_x"`
 	ClickPositionY *float64 `ch:"click_position_y"`
+	PagePositionX  *float64 `ch:"page_position_x"`
+	PagePositionY  *float64 `ch:"page_position_y"`
+	ViewportWidth  *uint16  `ch:"viewport_width"`
+	ViewportHeight *uint16  `ch:"viewport_height"`
 
 	//UTM parameters
 	UTMParameters map[string]string `ch:"utm_parameters"`
 
 	// Custom properties
-	CustomProperties string `ch:"custom_properties"`
+	CustomProperties    string            `ch:"custom_properties"`
+	CustomPropertiesMap map[string]string `ch:"custom_properties_map"`
 
 	// Organization hierarchy
 	ProjectID      string `ch:"project_id"`
 	OrganizationID string `ch:"organization_id"`
 
 	// Location
-	LocationCountryISO *string `ch:"location_country_iso"`
-	LocationCity       *string `ch:"location_city"`
+	LocationCountryISO  *string           `ch:"location_country_iso"`
+	LocationCity        *string           `ch:"location_city"`
+	LocationRegionISO   *string           `ch:"location_region_iso"`
+	LocationRegion      *string           `ch:"location_region"`
+	LocationPostalCode  *string           `ch:"location_postal_code"`
+	LocationLatitude    *float32          `ch:"location_latitude"`
+	LocationLongitude   *float32          `ch:"location_longitude"`
+	LocationTimezone    *string           `ch:"location_timezone"`
+	LocationCityNames   map[string]string `ch:"location_city_names"`
+	LocationRegionNames map[string]string `ch:"location_region_names"`
+
+	// Network
+	ASN            *uint32 `ch:"asn"`
+	ASOrganization *string `ch:"as_organization"`
 
 	// Metadata
 	CreatedAt time.Time `ch:"created_at,type:DateTime,default:now()"`
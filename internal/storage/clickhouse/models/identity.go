@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/go-clickhouse/ch"
+)
+
+// Identity links a visitor to a user of the tracked product, the identity stored last replaces older ones
+type Identity struct {
+	ch.CHModel `ch:"identities,engine:ReplacingMergeTree(server_timestamp_utc),order:organization_id,order:project_id,order:visitor_id"`
+
+	OrganizationID string `ch:"organization_id"`
+	ProjectID      string `ch:"project_id"`
+	VisitorID      string `ch:"visitor_id"`
+	UserID         string `ch:"user_id"`
+
+	IdentifiedAt       time.Time `ch:"identified_at"`
+	ServerTimestampUTC time.Time `ch:"server_timestamp_utc,default:now()"`
+}
+
+// UserTraits holds the traits of the identify event of a user stored last, identify events without traits and
+// aliases leave them untouched
+type UserTraits struct {
+	ch.CHModel `ch:"user_traits,engine:ReplacingMergeTree(server_timestamp_utc),order:organization_id,order:project_id,order:user_id"`
+
+	OrganizationID string `ch:"organization_id"`
+	ProjectID      string `ch:"project_id"`
+	UserID         string `ch:"user_id"`
+	Traits         string `ch:"traits"`
+
+	IdentifiedAt       time.Time `ch:"identified_at"`
+	ServerTimestampUTC time.Time `ch:"server_timestamp_utc,default:now()"`
+}
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Inactivity after which the next event of a visitor starts a new session
+ALTER TABLE projects
+    ADD COLUMN session_timeout_minutes INTEGER NOT NULL DEFAULT 30
+    CHECK (session_timeout_minutes BETWEEN 1 AND 1440);
+
+-- +goose Down
+ALTER TABLE projects DROP COLUMN IF EXISTS session_timeout_minutes;
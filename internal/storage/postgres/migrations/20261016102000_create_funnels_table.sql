@@ -0,0 +1,21 @@
+-- +goose Up
+-- Create funnels table, steps are stored in order as a JSON array
+CREATE TABLE funnels (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    steps JSONB NOT NULL,
+    window_minutes INTEGER NOT NULL DEFAULT 1440,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_funnels_project_id ON funnels(project_id);
+
+CREATE TRIGGER update_funnels_updated_at BEFORE UPDATE ON funnels
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS update_funnels_updated_at ON funnels;
+DROP INDEX IF EXISTS idx_funnels_project_id;
+DROP TABLE IF EXISTS funnels;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Create goals table, a goal is reached by a page view, a custom event or a click
+CREATE TABLE goals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(50) NOT NULL, -- page_view, event, click
+    match VARCHAR(50) NOT NULL DEFAULT 'equals', -- equals, starts_with, contains, pattern
+    value VARCHAR(1024) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_goals_project_id ON goals(project_id);
+
+CREATE TRIGGER update_goals_updated_at BEFORE UPDATE ON goals
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS update_goals_updated_at ON goals;
+DROP INDEX IF EXISTS idx_goals_project_id;
+DROP TABLE IF EXISTS goals;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Cookieless projects derive visitor IDs server-side instead of reading the visitor_id cookie
+ALTER TABLE projects ADD COLUMN cookieless BOOLEAN NOT NULL DEFAULT false;
+
+-- Daily salts visitor IDs of cookieless projects are derived with, salts older than a day are deleted
+-- so visitor IDs cannot be recomputed once their day is over
+CREATE TABLE visitor_salts (
+    day DATE PRIMARY KEY,
+    salt BYTEA NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS visitor_salts;
+ALTER TABLE projects DROP COLUMN IF EXISTS cookieless;
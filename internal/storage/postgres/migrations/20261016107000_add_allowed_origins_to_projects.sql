@@ -0,0 +1,6 @@
+-- +goose Up
+-- Host patterns such as *.example.com events are accepted from besides the domain of the project
+ALTER TABLE projects ADD COLUMN allowed_origins TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE projects DROP COLUMN IF EXISTS allowed_origins;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Events an organization may ingest per calendar month (UTC), unlimited when NULL
+ALTER TABLE organizations ADD COLUMN monthly_event_quota BIGINT CHECK (monthly_event_quota >= 0);
+
+-- Events ingested per project and month, usage outlives deleted projects so it still counts against the quota
+CREATE TABLE organization_usage (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL,
+    month DATE NOT NULL,
+    events BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organization_id, project_id, month)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS organization_usage;
+ALTER TABLE organizations DROP COLUMN IF EXISTS monthly_event_quota;
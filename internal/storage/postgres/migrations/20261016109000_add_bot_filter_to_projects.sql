@@ -0,0 +1,6 @@
+-- +goose Up
+-- How events detected as bots are handled: tagged with is_bot, dropped, or not detected at all
+ALTER TABLE projects ADD COLUMN bot_filter TEXT NOT NULL DEFAULT 'tag' CHECK (bot_filter IN ('off', 'tag', 'drop'));
+
+-- +goose Down
+ALTER TABLE projects DROP COLUMN IF EXISTS bot_filter;
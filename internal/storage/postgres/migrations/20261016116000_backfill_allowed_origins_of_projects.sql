@@ -0,0 +1,16 @@
+-- +goose Up
+-- Projects created before allowed origins were enforced accepted events from any origin, they keep accepting events
+-- from the host of their domain and its subdomains. The host is normalized like origins.Normalize, without www.
+UPDATE projects p
+SET allowed_origins = ARRAY[d.host, '*.' || d.host]
+FROM (
+    SELECT id, regexp_replace(
+        rtrim(split_part(split_part(regexp_replace(lower(trim(domain)), '^[a-z][a-z0-9+.-]*://', ''), '/', 1), ':', 1), '.'),
+        '^www\.', ''
+    ) AS host
+    FROM projects
+) d
+WHERE p.id = d.id AND p.allowed_origins = '{}' AND d.host ~ '^[a-z0-9-]+(\.[a-z0-9-]+)+$';
+
+-- +goose Down
+-- backfilled origins cannot be told apart from the ones set afterwards, they are kept
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+type Funnel struct {
+	bun.BaseModel `json:"-" bun:"table:funnels,alias:f"`
+
+	ID            string       `json:"id" bun:",pk,type:uuid,default:gen_random_uuid()" example:"770e8400-e29b-41d4-a716-446655440002"`
+	ProjectID     string       `json:"project_id" bun:",notnull" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name          string       `json:"name" bun:",notnull" example:"Signup"`
+	Steps         []FunnelStep `json:"steps" bun:",type:jsonb,notnull"`
+	WindowMinutes int          `json:"window_minutes" bun:",notnull,default:1440" example:"1440"`
+	CreatedAt     time.Time    `json:"created_at" bun:",notnull,default:current_timestamp" example:"2024-01-15T10:30:00Z"`
+	UpdatedAt     time.Time    `json:"updated_at" bun:",notnull,default:current_timestamp" example:"2024-01-15T10:30:00Z"`
+
+	Project *Project `json:"project,omitempty" bun:"rel:belongs-to,join:project_id=id"`
+}
+
+// FunnelStep matches the events completing a step, page views by their path, custom events by their name
+// and clicks by the clicked element. Pattern matches the whole value with * standing for any characters.
+type FunnelStep struct {
+	Type  string `json:"type" validate:"required,oneof=page_view event click" example:"page_view"`
+	Match string `json:"match" validate:"omitempty,oneof=equals starts_with contains pattern" example:"equals"`
+	Value string `json:"value" validate:"required,max=1024" example:"/pricing"`
+}
+
+// Funnel step types
+const (
+	FunnelStepPageView = "page_view"
+	FunnelStepEvent    = "event"
+	FunnelStepClick    = "click"
+)
+
+// Funnel step matches, steps without a match compare for equality
+const (
+	FunnelMatchEquals     = "equals"
+	FunnelMatchStartsWith = "starts_with"
+	FunnelMatchContains   = "contains"
+	FunnelMatchPattern    = "pattern"
+)
+
+// DefaultFunnelWindowMinutes is the time a visitor has to complete a funnel once the first step is reached
+const DefaultFunnelWindowMinutes = 1440
+
+// TableName returns the table name for the Funnel model
+func (*Funnel) TableName() string {
+	return "funnels"
+}
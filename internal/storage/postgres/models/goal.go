@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Goal is reached by the events matching it, goals match events the same way funnel steps do
+type Goal struct {
+	bun.BaseModel `json:"-" bun:"table:goals,alias:g"`
+
+	ID        string    `json:"id" bun:",pk,type:uuid,default:gen_random_uuid()" example:"880e8400-e29b-41d4-a716-446655440003"`
+	ProjectID string    `json:"project_id" bun:",notnull" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name      string    `json:"name" bun:",notnull" example:"Visited pricing"`
+	Type      string    `json:"type" bun:",notnull" example:"page_view"`
+	Match     string    `json:"match" bun:",notnull,default:'equals'" example:"pattern"`
+	Value     string    `json:"value" bun:",notnull" example:"/pricing/*"`
+	CreatedAt time.Time `json:"created_at" bun:",notnull,default:current_timestamp" example:"2024-01-15T10:30:00Z"`
+	UpdatedAt time.Time `json:"updated_at" bun:",notnull,default:current_timestamp" example:"2024-01-15T10:30:00Z"`
+
+	Project *Project `json:"project,omitempty" bun:"rel:belongs-to,join:project_id=id"`
+}
+
+// MaxProjectGoals is the number of goals a project may have
+const MaxProjectGoals = 50
+
+// Step returns the condition of the goal as a funnel step
+func (g *Goal) Step() FunnelStep {
+	return FunnelStep{Type: g.Type, Match: g.Match, Value: g.Value}
+}
+
+// TableName returns the table name for the Goal model
+func (*Goal) TableName() string {
+	return "goals"
+}
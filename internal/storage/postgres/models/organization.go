@@ -15,6 +15,9 @@ type Organization struct {
 	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
 	UpdatedAt time.Time `json:"updated_at" bun:",nullzero,notnull,default:current_timestamp"`
 
+	// MonthlyEventQuota is the number of events the organization may ingest per month, unlimited when nil
+	MonthlyEventQuota *int64 `json:"monthly_event_quota" bun:",nullzero"`
+
 	// Relations
 	Members []Account `json:"members,omitempty" bun:"m2m:organization_members,join:Organization=Account"`
 }
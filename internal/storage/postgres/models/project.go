@@ -2,6 +2,7 @@ package models
 
 import (
 	"time"
+	"zori/internal/origins"
 
 	"github.com/uptrace/bun"
 )
@@ -9,15 +10,48 @@ import (
 type Project struct {
 	bun.BaseModel `json:"-" bun:"table:projects,alias:p"`
 
-	ID                   string     `json:"id" bun:",pk,type:uuid,default:gen_random_uuid()" example:"550e8400-e29b-41d4-a716-446655440000"`
-	OrganizationID       string     `json:"organization_id" bun:",notnull" example:"660e8400-e29b-41d4-a716-446655440001"`
-	Name                 string     `json:"name" bun:",notnull" example:"My Awesome Project"`
-	Domain               string     `json:"domain" bun:",notnull" example:"https://example.com"`
-	AllowLocalHost       bool       `json:"allow_local_host" bun:",notnull,default:false" example:"false"`
-	FirstEventReceivedAt *time.Time `json:"first_event_received_at" bun:",null" example:"2024-01-15T10:30:00Z"`
-	ProjectToken         string     `json:"project_token" bun:",notnull" example:"zori_pt_1234567890"`
-	CreatedAt            time.Time  `json:"created_at" bun:",notnull,default:current_timestamp" example:"2024-01-15T10:30:00Z"`
-	UpdatedAt            time.Time  `json:"updated_at" bun:",notnull,default:current_timestamp" example:"2024-01-15T10:30:00Z"`
+	ID                    string     `json:"id" bun:",pk,type:uuid,default:gen_random_uuid()" example:"550e8400-e29b-41d4-a716-446655440000"`
+	OrganizationID        string     `json:"organization_id" bun:",notnull" example:"660e8400-e29b-41d4-a716-446655440001"`
+	Name                  string     `json:"name" bun:",notnull" example:"My Awesome Project"`
+	Domain                string     `json:"domain" bun:",notnull" example:"https://example.com"`
+	AllowLocalHost        bool       `json:"allow_local_host" bun:",notnull,default:false" example:"false"`
+	SessionTimeoutMinutes int        `json:"session_timeout_minutes" bun:",notnull,default:30" example:"30"`
+	Cookieless            bool       `json:"cookieless" bun:",notnull,default:false" example:"false"`
+	AllowedOrigins        []string   `json:"allowed_origins" bun:",array,notnull,default:'{}'" example:"*.example.com"`
+	BotFilter             string     `json:"bot_filter" bun:",nullzero,notnull,default:'tag'" example:"tag"`
+	FirstEventReceivedAt  *time.Time `json:"first_event_received_at" bun:",null" example:"2024-01-15T10:30:00Z"`
+	ProjectToken          string     `json:"project_token" bun:",notnull" example:"zori_pt_1234567890"`
+	CreatedAt             time.Time  `json:"created_at" bun:",notnull,default:current_timestamp" example:"2024-01-15T10:30:00Z"`
+	UpdatedAt             time.Time  `json:"updated_at" bun:",notnull,default:current_timestamp" example:"2024-01-15T10:30:00Z"`
 
 	Organization *Organization `json:"organization,omitempty" bun:"rel:belongs-to,join:organization_id=id"`
 }
+
+// DefaultSessionTimeoutMinutes is the inactivity after which the next event of a visitor starts a new session
+const DefaultSessionTimeoutMinutes = 30
+
+// Bot filters decide what happens to events detected as bots: BotFilterTag stores them with is_bot set
+// so reports leave them out, BotFilterDrop does not store them and BotFilterOff stores every event as is
+const (
+	BotFilterOff  = "off"
+	BotFilterTag  = "tag"
+	BotFilterDrop = "drop"
+)
+
+// OriginPatterns returns the host patterns events are accepted from, the host of the project domain and the allowed origins
+func (p *Project) OriginPatterns() []string {
+	patterns := make([]string, 0, len(p.AllowedOrigins)+1)
+	if domain, err := origins.Normalize(p.Domain); err == nil {
+		patterns = append(patterns, domain)
+	}
+
+	return append(patterns, p.AllowedOrigins...)
+}
+
+// SessionTimeout returns the inactivity after which the next event of a visitor starts a new session
+func (p *Project) SessionTimeout() time.Duration {
+	if p.SessionTimeoutMinutes <= 0 {
+		return DefaultSessionTimeoutMinutes * time.Minute
+	}
+	return time.Duration(p.SessionTimeoutMinutes) * time.Minute
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// OrganizationUsage holds the events a project of an organization ingested in a calendar month
+type OrganizationUsage struct {
+	bun.BaseModel `json:"-" bun:"table:organization_usage,alias:ou"`
+
+	OrganizationID string    `json:"organization_id" bun:",pk,type:uuid" example:"660e8400-e29b-41d4-a716-446655440001"`
+	ProjectID      string    `json:"project_id" bun:",pk,type:uuid" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Month          time.Time `json:"month" bun:",pk,type:date" example:"2024-01-01T00:00:00Z"`
+	Events         int64     `json:"events" bun:",notnull" example:"125000"`
+	UpdatedAt      time.Time `json:"updated_at" bun:",notnull,default:current_timestamp" example:"2024-01-15T10:30:00Z"`
+}
+
+// UsageMonth returns the start of the UTC calendar month usage at t is counted in
+func UsageMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
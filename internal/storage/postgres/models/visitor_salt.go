@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// VisitorSalt is the salt visitor IDs of cookieless projects are derived with on a single UTC day
+type VisitorSalt struct {
+	bun.BaseModel `json:"-" bun:"table:visitor_salts,alias:vs"`
+
+	Day       time.Time `json:"day" bun:",pk,type:date"`
+	Salt      []byte    `json:"-" bun:",notnull"`
+	CreatedAt time.Time `json:"created_at" bun:",notnull,default:current_timestamp"`
+}
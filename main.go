@@ -10,6 +10,9 @@ import (
 	"time"
 
 	"zori/di"
+	"zori/internal/config"
+	"zori/internal/natsstream"
+	eventsServices "zori/services/events/services"
 
 	"github.com/urfave/cli/v3"
 )
@@ -64,6 +67,43 @@ func main() {
 				Usage:   "Start ingestion HTTP server",
 				Action:  runIngestionServer,
 			},
+			{
+				Name:    "deadletter",
+				Aliases: []string{"dl"},
+				Usage:   "Inspect and re-drive events the processor could not handle",
+				Commands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "List dead-lettered events, oldest first",
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:    "limit",
+								Aliases: []string{"l"},
+								Value:   20,
+								Usage:   "Maximum number of events to list",
+							},
+						},
+						Action: runDeadLetterList,
+					},
+					{
+						Name:  "redrive",
+						Usage: "Publish dead-lettered events back to the raw events stream",
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:    "limit",
+								Aliases: []string{"l"},
+								Value:   100,
+								Usage:   "Maximum number of events to re-drive",
+							},
+							&cli.Uint64Flag{
+								Name:  "sequence",
+								Usage: "Re-drive only the event with this dead-letter sequence",
+							},
+						},
+						Action: runDeadLetterRedrive,
+					},
+				},
+			},
 		},
 	}
 
@@ -143,3 +183,56 @@ func runServer(ctx context.Context, cmd *cli.Command) error {
 	fmt.Println("Application stopped successfully")
 	return nil
 }
+
+func newDeadLetterService() *eventsServices.DeadLetterService {
+	cfg := config.NewConfig()
+	return eventsServices.NewDeadLetterService(natsstream.NewStream(cfg))
+}
+
+func runDeadLetterList(ctx context.Context, cmd *cli.Command) error {
+	deadLetterService := newDeadLetterService()
+
+	entries, err := deadLetterService.List(ctx, int(cmd.Int("limit")))
+	if err != nil {
+		return fmt.Errorf("failed to list dead-lettered events: %w", err)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No dead-lettered events")
+		return nil
+	}
+
+	for _, entry := range entries {
+		fmt.Printf("#%d failed at %s in stage %q after %d deliveries: %s\n",
+			entry.Sequence, entry.FailedAt.Format(time.RFC3339), entry.Stage, entry.Deliveries, entry.Reason)
+		fmt.Printf("    %s\n", string(entry.Frame))
+	}
+
+	return nil
+}
+
+func runDeadLetterRedrive(ctx context.Context, cmd *cli.Command) error {
+	deadLetterService := newDeadLetterService()
+
+	if sequence := cmd.Uint64("sequence"); sequence != 0 {
+		if err := deadLetterService.RedriveSequence(ctx, sequence); err != nil {
+			return err
+		}
+
+		fmt.Printf("Re-drove dead-lettered event #%d\n", sequence)
+		return nil
+	}
+
+	limit := int(cmd.Int("limit"))
+	if limit <= 0 {
+		return fmt.Errorf("--limit must be positive, got %d", limit)
+	}
+
+	redriven, err := deadLetterService.Redrive(ctx, limit)
+	if err != nil {
+		return fmt.Errorf("re-drove %d events before failing: %w", redriven, err)
+	}
+
+	fmt.Printf("Re-drove %d dead-lettered events\n", redriven)
+	return nil
+}
@@ -0,0 +1,25 @@
+package analytics
+
+import (
+	"zori/services/analytics/data"
+	"zori/services/analytics/services"
+	"zori/services/analytics/web"
+
+	"go.uber.org/fx"
+)
+
+func BuildAnalyticsDIContainer() fx.Option {
+	return fx.Module("analytics",
+		fx.Provide(
+			data.NewAnalyticsData,
+			data.NewFunnelData,
+			services.NewAnalyticsService,
+		),
+	)
+}
+
+func BuildAnalyticsWebDIContainer() fx.Option {
+	return fx.Module("analytics_web",
+		fx.Invoke(web.RegisterRoutes),
+	)
+}
@@ -0,0 +1,86 @@
+package data
+
+import (
+	"context"
+	"time"
+	"zori/internal/storage/clickhouse"
+	"zori/services/analytics/filters"
+	"zori/services/analytics/types"
+
+	goclick "github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// Conditions classifying stored events, custom events carry a name and clicks carry the clicked element.
+const (
+	pageViewCondition    = "event_name IS NULL AND click_on IS NULL"
+	clickCondition       = "event_name IS NULL AND click_on IS NOT NULL"
+	customEventCondition = "event_name IS NOT NULL"
+)
+
+// Scope restricts a report to the events of a single project within a date range
+type Scope struct {
+	OrganizationID string
+	ProjectID      string
+	From           time.Time
+	To             time.Time
+	Filter         *filters.Filter
+	// SessionTimeout is the inactivity after which the next event of a visitor starts a new session
+	SessionTimeout time.Duration
+	// ServerTime selects events by the time they were received instead of the time of the client, so windows
+	// relative to now are not shifted by clients with a wrong clock
+	ServerTime bool
+}
+
+// Where returns the condition selecting events in scope along with its arguments, events of bots are never in scope
+// and neither are any events of visitors found to be bots after some of their events were stored
+func (s *Scope) Where() (string, []any) {
+	timestampColumn := "client_timestamp_utc"
+	if s.ServerTime {
+		timestampColumn = "server_timestamp_utc"
+	}
+
+	where := "organization_id = ? AND project_id = ? AND " + timestampColumn + " >= ? AND " + timestampColumn + " < ? AND NOT is_bot" +
+		" AND visitor_id NOT IN (SELECT visitor_id FROM bot_visitors WHERE organization_id = ? AND project_id = ?)"
+	args := []any{s.OrganizationID, s.ProjectID, s.From, s.To, s.OrganizationID, s.ProjectID}
+
+	if s.Filter != nil {
+		where += " AND " + s.Filter.SQL
+		args = append(args, s.Filter.Args...)
+	}
+
+	return where, args
+}
+
+type AnalyticsData struct {
+	db goclick.Conn
+}
+
+func NewAnalyticsData(db *clickhouse.ClickhouseDB) *AnalyticsData {
+	return &AnalyticsData{db: db.Db()}
+}
+
+func (a *AnalyticsData) Overview(ctx context.Context, scope *Scope) (*types.OverviewResponse, error) {
+	where, args := scope.Where()
+
+	overview := &types.OverviewResponse{From: scope.From, To: scope.To}
+	err := a.db.QueryRow(ctx, `
+		SELECT
+			uniqExact(visitor_id),
+			countIf(`+pageViewCondition+`),
+			countIf(`+clickCondition+`),
+			countIf(`+customEventCondition+`),
+			count()
+		FROM events
+		WHERE `+where, args...).
+		Scan(&overview.Visitors, &overview.PageViews, &overview.Clicks, &overview.CustomEvents, &overview.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	overview.People, err = a.People(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	return overview, nil
+}
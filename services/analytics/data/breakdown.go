@@ -0,0 +1,61 @@
+package data
+
+import (
+	"context"
+	"zori/services/analytics/types"
+)
+
+// Breakdown returns the top values of the dimension ordered by visitors, values which are empty are left out.
+// Share is the fraction of all visitors in scope who have the value. Session dimensions are attributed to sessions.
+func (a *AnalyticsData) Breakdown(ctx context.Context, scope *Scope, dimension string, limit int, offset int) (*types.BreakdownResponse, error) {
+	from, where, args, dimensionExpr := dimensionSource(scope, dimension)
+
+	response := &types.BreakdownResponse{
+		Dimension: dimension,
+		From:      scope.From,
+		To:        scope.To,
+		Rows:      []types.BreakdownRow{},
+	}
+
+	var totalVisitors uint64
+	err := a.db.QueryRow(ctx, `
+		SELECT
+			uniqExactIf(`+dimensionExpr+`, `+dimensionExpr+` != ''),
+			uniqExact(visitor_id)
+		FROM `+from+`
+		WHERE `+where, args...).
+		Scan(&response.Total, &totalVisitors)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := a.db.Query(ctx, `
+		SELECT
+			`+dimensionExpr+` AS value,
+			uniqExact(visitor_id) AS visitors,
+			countIf(`+pageViewCondition+`),
+			count()
+		FROM `+from+`
+		WHERE `+where+` AND value != ''
+		GROUP BY value
+		ORDER BY visitors DESC, value
+		LIMIT ? OFFSET ?`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row types.BreakdownRow
+		if err := rows.Scan(&row.Value, &row.Visitors, &row.Views, &row.Events); err != nil {
+			return nil, err
+		}
+
+		if totalVisitors > 0 {
+			row.Share = float64(row.Visitors) / float64(totalVisitors)
+		}
+		response.Rows = append(response.Rows, row)
+	}
+
+	return response, rows.Err()
+}
@@ -0,0 +1,57 @@
+package data
+
+import "sort"
+
+// Dimensions maps dimension names accepted by the API to the SQL expression reading them from the events table,
+// every expression evaluates to a String with missing values being empty.
+var Dimensions = map[string]string{
+	"page_path":       "page_path",
+	"host":            "host",
+	"referrer_domain": "ifNull(referrer_domain, '')",
+	"country":         "ifNull(toString(location_country_iso), '')",
+	"city":            "ifNull(location_city, '')",
+	"region":          "ifNull(location_region_iso, '')",
+	"timezone":        "ifNull(location_timezone, '')",
+	"isp":             "ifNull(as_organization, '')",
+	"browser":         "ifNull(browser_name, '')",
+	"os":              "ifNull(os_name, '')",
+	"device_type":     "ifNull(device_type, '')",
+	"event_name":      "ifNull(event_name, '')",
+	"utm_source":      "utm_source",
+	"utm_medium":      "utm_medium",
+	"utm_campaign":    "utm_campaign",
+	"channel":         "channel",
+	"source":          "source_name",
+}
+
+// SessionDimensions maps the dimensions of traffic sources to their column. Only the landing event of a session
+// carries where the visit came from, later events count as direct navigation within the site, so breakdowns by
+// these dimensions attribute the value of the landing event to every event of the session. Filters on them still
+// match the value of each event.
+var SessionDimensions = map[string]string{
+	"channel": "channel",
+	"source":  "source_name",
+}
+
+// dimensionSource returns the events to break down by the dimension as a table expression with its condition and
+// arguments, along with the expression reading the dimension from them
+func dimensionSource(scope *Scope, dimension string) (string, string, []any, string) {
+	if column, ok := SessionDimensions[dimension]; ok {
+		attributedEvents, args := sessionAttributedEventsQuery(scope, column)
+		return "(" + attributedEvents + ")", "1", args, "session_value"
+	}
+
+	where, args := scope.Where()
+	return "events", where, args, Dimensions[dimension]
+}
+
+// DimensionNames returns the names of all dimensions in alphabetical order
+func DimensionNames() []string {
+	names := make([]string, 0, len(Dimensions))
+	for name := range Dimensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,94 @@
+package data
+
+import (
+	"context"
+	"zori/internal/storage/postgres"
+	"zori/internal/storage/postgres/models"
+	"zori/services/analytics/types"
+
+	"github.com/uptrace/bun"
+)
+
+// FunnelData stores funnel definitions in Postgres, callers make sure the project belongs to the organization
+type FunnelData struct {
+	db *bun.DB
+}
+
+func NewFunnelData(db *postgres.PostgresDB) *FunnelData {
+	return &FunnelData{db: db.DB}
+}
+
+func (f *FunnelData) ListProjectFunnels(ctx context.Context, projectID string) ([]*models.Funnel, error) {
+	funnels := []*models.Funnel{}
+	err := f.db.NewSelect().
+		Model(&funnels).
+		Where("project_id = ?", projectID).
+		Order("created_at DESC").
+		Scan(ctx)
+	return funnels, err
+}
+
+func (f *FunnelData) GetFunnel(ctx context.Context, projectID string, funnelID string) (*models.Funnel, error) {
+	funnel := &models.Funnel{}
+	err := f.db.NewSelect().
+		Model(funnel).
+		Where("id = ?", funnelID).
+		Where("project_id = ?", projectID).
+		Scan(ctx)
+	return funnel, err
+}
+
+func (f *FunnelData) CreateFunnel(ctx context.Context, projectID string, req *types.FunnelRequest) (*models.Funnel, error) {
+	funnel := &models.Funnel{
+		ProjectID:     projectID,
+		Name:          req.Name,
+		Steps:         req.Steps,
+		WindowMinutes: req.WindowMinutes,
+	}
+
+	_, err := f.db.NewInsert().
+		Model(funnel).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return funnel, nil
+}
+
+func (f *FunnelData) UpdateFunnel(ctx context.Context, projectID string, funnelID string, req *types.FunnelRequest) (*models.Funnel, error) {
+	funnel := &models.Funnel{}
+
+	_, err := f.db.NewUpdate().
+		Model(funnel).
+		Set("name = ?", req.Name).
+		Set("steps = ?", req.Steps).
+		Set("window_minutes = ?", req.WindowMinutes).
+		Where("id = ?", funnelID).
+		Where("project_id = ?", projectID).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return funnel, nil
+}
+
+func (f *FunnelData) DeleteFunnel(ctx context.Context, projectID string, funnelID string) error {
+	_, err := f.db.NewDelete().
+		Model(&models.Funnel{}).
+		Where("id = ?", funnelID).
+		Where("project_id = ?", projectID).
+		Exec(ctx)
+	return err
+}
+
+func (f *FunnelData) FunnelExists(ctx context.Context, projectID string, funnelID string) (bool, error) {
+	return f.db.NewSelect().
+		Model(&models.Funnel{}).
+		Where("id = ?", funnelID).
+		Where("project_id = ?", projectID).
+		Exists(ctx)
+}
@@ -0,0 +1,137 @@
+package data
+
+import (
+	"context"
+	"strings"
+	"zori/internal/storage/postgres/models"
+	"zori/services/analytics/types"
+)
+
+// StepCondition returns the condition selecting the events which complete the step along with its arguments
+func StepCondition(step models.FunnelStep) (string, []any) {
+	var typeCondition, valueExpr string
+	switch step.Type {
+	case models.FunnelStepEvent:
+		typeCondition, valueExpr = customEventCondition, "ifNull(event_name, '')"
+	case models.FunnelStepClick:
+		typeCondition, valueExpr = clickCondition, "ifNull(click_on, '')"
+	default:
+		typeCondition, valueExpr = pageViewCondition, "page_path"
+	}
+
+	switch step.Match {
+	case models.FunnelMatchStartsWith:
+		return "(" + typeCondition + " AND startsWith(" + valueExpr + ", ?))", []any{step.Value}
+	case models.FunnelMatchContains:
+		return "(" + typeCondition + " AND position(" + valueExpr + ", ?) > 0)", []any{step.Value}
+	case models.FunnelMatchPattern:
+		return "(" + typeCondition + " AND " + valueExpr + " LIKE ?)", []any{likePattern(step.Value)}
+	default:
+		return "(" + typeCondition + " AND " + valueExpr + " = ?)", []any{step.Value}
+	}
+}
+
+// likePattern converts a pattern where * stands for any characters to a LIKE pattern
+func likePattern(pattern string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`, `*`, `%`).Replace(pattern)
+}
+
+// Funnel counts the visitors reaching every step of the funnel in order, later steps have to be reached
+// within the window of the funnel after the first one and all of them within the scope.
+func (a *AnalyticsData) Funnel(ctx context.Context, scope *Scope, funnel *models.Funnel) (*types.FunnelReportResponse, error) {
+	where, args := scope.Where()
+
+	conditions := make([]string, len(funnel.Steps))
+	var conditionArgs []any
+	for idx, step := range funnel.Steps {
+		condition, stepArgs := StepCondition(step)
+		conditions[idx] = condition
+		conditionArgs = append(conditionArgs, stepArgs...)
+	}
+
+	// conditions appear twice, once as windowFunnel arguments and once to skip events not matching any step
+	queryArgs := []any{funnel.WindowMinutes * 60}
+	queryArgs = append(queryArgs, conditionArgs...)
+	queryArgs = append(queryArgs, args...)
+	queryArgs = append(queryArgs, conditionArgs...)
+
+	rows, err := a.db.Query(ctx, `
+		SELECT level, count()
+		FROM (
+			SELECT
+				visitor_id,
+				windowFunnel(?)(toDateTime(client_timestamp_utc), `+strings.Join(conditions, ", ")+`) AS level
+			FROM events
+			WHERE `+where+` AND (`+strings.Join(conditions, " OR ")+`)
+			GROUP BY visitor_id
+		)
+		WHERE level > 0
+		GROUP BY level`, queryArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	levelVisitors := make(map[int]uint64, len(funnel.Steps))
+	for rows.Next() {
+		var (
+			level    uint8
+			visitors uint64
+		)
+		if err := rows.Scan(&level, &visitors); err != nil {
+			return nil, err
+		}
+		levelVisitors[int(level)] = visitors
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &types.FunnelReportResponse{
+		FunnelID:      funnel.ID,
+		Name:          funnel.Name,
+		From:          scope.From,
+		To:            scope.To,
+		WindowMinutes: funnel.WindowMinutes,
+		Steps:         FunnelSteps(funnel.Steps, levelVisitors),
+	}, nil
+}
+
+// FunnelSteps turns the number of visitors by the last step they reached into the conversion of every step,
+// a visitor who reached a step also completed all the steps before it.
+func FunnelSteps(steps []models.FunnelStep, levelVisitors map[int]uint64) []types.FunnelStepResult {
+	results := make([]types.FunnelStepResult, len(steps))
+
+	var reached uint64
+	for idx := len(steps) - 1; idx >= 0; idx-- {
+		reached += levelVisitors[idx+1]
+		results[idx] = types.FunnelStepResult{
+			Step:     idx + 1,
+			Type:     steps[idx].Type,
+			Match:    steps[idx].Match,
+			Value:    steps[idx].Value,
+			Visitors: reached,
+		}
+	}
+
+	for idx := range results {
+		if results[0].Visitors > 0 {
+			results[idx].ConversionRate = float64(results[idx].Visitors) / float64(results[0].Visitors)
+		}
+
+		if idx == 0 {
+			if results[idx].Visitors > 0 {
+				results[idx].StepConversionRate = 1
+			}
+			continue
+		}
+
+		previous := results[idx-1].Visitors
+		results[idx].DropOff = previous - results[idx].Visitors
+		if previous > 0 {
+			results[idx].StepConversionRate = float64(results[idx].Visitors) / float64(previous)
+		}
+	}
+
+	return results
+}
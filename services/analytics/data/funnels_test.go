@@ -0,0 +1,97 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+	"zori/internal/storage/postgres/models"
+)
+
+func TestStepCondition(t *testing.T) {
+	tests := []struct {
+		name         string
+		step         models.FunnelStep
+		expectedSQL  string
+		expectedArgs []any
+	}{
+		{
+			"page view",
+			models.FunnelStep{Type: models.FunnelStepPageView, Match: models.FunnelMatchEquals, Value: "/pricing"},
+			"(" + pageViewCondition + " AND page_path = ?)",
+			[]any{"/pricing"},
+		},
+		{
+			"event prefix",
+			models.FunnelStep{Type: models.FunnelStepEvent, Match: models.FunnelMatchStartsWith, Value: "signup_"},
+			"(" + customEventCondition + " AND startsWith(ifNull(event_name, ''), ?))",
+			[]any{"signup_"},
+		},
+		{
+			"page pattern",
+			models.FunnelStep{Type: models.FunnelStepPageView, Match: models.FunnelMatchPattern, Value: "/blog/*_draft%"},
+			"(" + pageViewCondition + " AND page_path LIKE ?)",
+			[]any{`/blog/%\_draft\%`},
+		},
+		{
+			"click contains",
+			models.FunnelStep{Type: models.FunnelStepClick, Match: models.FunnelMatchContains, Value: "#buy"},
+			"(" + clickCondition + " AND position(ifNull(click_on, ''), ?) > 0)",
+			[]any{"#buy"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sql, args := StepCondition(test.step)
+			if sql != test.expectedSQL {
+				t.Errorf("Expected SQL %q, got %q", test.expectedSQL, sql)
+			}
+			if !reflect.DeepEqual(args, test.expectedArgs) {
+				t.Errorf("Expected args %v, got %v", test.expectedArgs, args)
+			}
+		})
+	}
+}
+
+func TestFunnelSteps(t *testing.T) {
+	steps := []models.FunnelStep{
+		{Type: models.FunnelStepPageView, Value: "/"},
+		{Type: models.FunnelStepPageView, Value: "/pricing"},
+		{Type: models.FunnelStepEvent, Value: "signup"},
+	}
+
+	// 50 visitors stopped after the first step, 30 after the second and 20 completed the funnel
+	results := FunnelSteps(steps, map[int]uint64{1: 50, 2: 30, 3: 20})
+
+	expectedVisitors := []uint64{100, 50, 20}
+	expectedDropOff := []uint64{0, 50, 30}
+	expectedConversion := []float64{1, 0.5, 0.2}
+	expectedStepConversion := []float64{1, 0.5, 0.4}
+
+	for idx, result := range results {
+		if result.Step != idx+1 {
+			t.Errorf("Expected step %d, got %d", idx+1, result.Step)
+		}
+		if result.Visitors != expectedVisitors[idx] {
+			t.Errorf("Step %d: expected %d visitors, got %d", idx+1, expectedVisitors[idx], result.Visitors)
+		}
+		if result.DropOff != expectedDropOff[idx] {
+			t.Errorf("Step %d: expected drop-off %d, got %d", idx+1, expectedDropOff[idx], result.DropOff)
+		}
+		if result.ConversionRate != expectedConversion[idx] {
+			t.Errorf("Step %d: expected conversion %v, got %v", idx+1, expectedConversion[idx], result.ConversionRate)
+		}
+		if result.StepConversionRate != expectedStepConversion[idx] {
+			t.Errorf("Step %d: expected step conversion %v, got %v", idx+1, expectedStepConversion[idx], result.StepConversionRate)
+		}
+	}
+}
+
+func TestFunnelStepsWithoutVisitors(t *testing.T) {
+	results := FunnelSteps([]models.FunnelStep{{}, {}}, map[int]uint64{})
+
+	for _, result := range results {
+		if result.Visitors != 0 || result.ConversionRate != 0 || result.StepConversionRate != 0 || result.DropOff != 0 {
+			t.Errorf("Expected empty step, got %+v", result)
+		}
+	}
+}
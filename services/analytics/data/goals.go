@@ -0,0 +1,109 @@
+package data
+
+import (
+	"context"
+	"strings"
+	"zori/internal/storage/postgres/models"
+	"zori/services/analytics/types"
+)
+
+// GoalConversions counts the visitors and events reaching every goal, the conversion rate of a goal is the share
+// of visitors who reached it. With a dimension the same counts are returned for its top values by visitors, session
+// dimensions credit conversions to the value the session landed with.
+func (a *AnalyticsData) GoalConversions(ctx context.Context, scope *Scope, goals []*models.Goal, dimension string, limit int) (*types.GoalConversionsResponse, error) {
+	where, args := scope.Where()
+
+	response := &types.GoalConversionsResponse{
+		From:      scope.From,
+		To:        scope.To,
+		Dimension: dimension,
+		Goals:     []types.GoalConversion{},
+	}
+
+	if len(goals) == 0 {
+		return response, nil
+	}
+
+	visitorsExprs := make([]string, len(goals))
+	conversionsExprs := make([]string, len(goals))
+	var conditionArgs []any
+	for idx, goal := range goals {
+		condition, goalArgs := StepCondition(goal.Step())
+		visitorsExprs[idx] = "uniqExactIf(visitor_id, " + condition + ")"
+		conversionsExprs[idx] = "countIf(" + condition + ")"
+		conditionArgs = append(conditionArgs, goalArgs...)
+	}
+
+	// every condition appears twice, first in the visitors array and then in the conversions array
+	selectExpr := `
+			uniqExact(visitor_id) AS visitors,
+			[` + strings.Join(visitorsExprs, ", ") + `],
+			[` + strings.Join(conversionsExprs, ", ") + `]`
+	selectArgs := append(append([]any{}, conditionArgs...), conditionArgs...)
+
+	var (
+		goalVisitors    []uint64
+		goalConversions []uint64
+	)
+	err := a.db.QueryRow(ctx, `
+		SELECT `+selectExpr+`
+		FROM events
+		WHERE `+where, append(selectArgs, args...)...).
+		Scan(&response.Visitors, &goalVisitors, &goalConversions)
+	if err != nil {
+		return nil, err
+	}
+	response.Goals = goalConversionResults(goals, response.Visitors, goalVisitors, goalConversions)
+
+	if dimension == "" {
+		return response, nil
+	}
+
+	from, dimensionWhere, dimensionArgs, dimensionExpr := dimensionSource(scope, dimension)
+	queryArgs := append(selectArgs, dimensionArgs...)
+	queryArgs = append(queryArgs, limit)
+
+	rows, err := a.db.Query(ctx, `
+		SELECT
+			`+dimensionExpr+` AS value,`+selectExpr+`
+		FROM `+from+`
+		WHERE `+dimensionWhere+` AND value != ''
+		GROUP BY value
+		ORDER BY visitors DESC, value
+		LIMIT ?`, queryArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	response.Rows = []types.GoalConversionsRow{}
+	for rows.Next() {
+		var row types.GoalConversionsRow
+		if err := rows.Scan(&row.Value, &row.Visitors, &goalVisitors, &goalConversions); err != nil {
+			return nil, err
+		}
+
+		row.Goals = goalConversionResults(goals, row.Visitors, goalVisitors, goalConversions)
+		response.Rows = append(response.Rows, row)
+	}
+
+	return response, rows.Err()
+}
+
+func goalConversionResults(goals []*models.Goal, visitors uint64, goalVisitors []uint64, goalConversions []uint64) []types.GoalConversion {
+	results := make([]types.GoalConversion, len(goals))
+	for idx, goal := range goals {
+		results[idx] = types.GoalConversion{
+			GoalID:      goal.ID,
+			Name:        goal.Name,
+			Visitors:    goalVisitors[idx],
+			Conversions: goalConversions[idx],
+		}
+
+		if visitors > 0 {
+			results[idx].ConversionRate = float64(goalVisitors[idx]) / float64(visitors)
+		}
+	}
+
+	return results
+}
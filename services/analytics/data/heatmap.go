@@ -0,0 +1,94 @@
+package data
+
+import (
+	"context"
+	"zori/services/analytics/types"
+)
+
+// MaxHeatmapCells caps the cells returned for a page, the longest pages are cut at the bottom
+const MaxHeatmapCells = 20000
+
+// heatmapClickCondition selects the clicks which can be placed on a heatmap, positions within the clicked element
+// cannot, so only clicks with a page position are
+const heatmapClickCondition = clickCondition + ` AND page_position_x >= 0 AND page_position_y >= 0 AND viewport_width > 0`
+
+// Heatmap bins the clicks on the page into square cells. Page positions are divided by the viewport width the click
+// happened in, so clicks from different screens line up when the page is rendered at any width: a cell spans
+// 1/bins of the page width horizontally and the same distance vertically.
+func (a *AnalyticsData) Heatmap(ctx context.Context, scope *Scope, pagePath string, bins int, limit int) (*types.HeatmapResponse, error) {
+	where, args := scope.Where()
+	where += " AND page_path = ? AND " + heatmapClickCondition
+	args = append(args, pagePath)
+
+	response := &types.HeatmapResponse{
+		PagePath: pagePath,
+		From:     scope.From,
+		To:       scope.To,
+		Bins:     bins,
+		Cells:    []types.HeatmapCell{},
+		Elements: []types.HeatmapElement{},
+	}
+
+	cellRows, err := a.db.Query(ctx, `
+		SELECT
+			toUInt32(least(floor(page_position_x / viewport_width * ?), ? - 1)) AS x,
+			toUInt32(floor(page_position_y / viewport_width * ?)) AS y,
+			count() AS clicks
+		FROM events
+		WHERE `+where+`
+		GROUP BY x, y
+		ORDER BY y, x
+		LIMIT ?`, append([]any{bins, bins, bins}, append(args, MaxHeatmapCells)...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer cellRows.Close()
+
+	var maxClicks uint64
+	for cellRows.Next() {
+		var cell types.HeatmapCell
+		if err := cellRows.Scan(&cell.X, &cell.Y, &cell.Clicks); err != nil {
+			return nil, err
+		}
+
+		response.Clicks += cell.Clicks
+		maxClicks = max(maxClicks, cell.Clicks)
+		response.Cells = append(response.Cells, cell)
+	}
+	if err := cellRows.Err(); err != nil {
+		return nil, err
+	}
+
+	for idx := range response.Cells {
+		response.Cells[idx].Density = float64(response.Cells[idx].Clicks) / float64(maxClicks)
+	}
+
+	elementRows, err := a.db.Query(ctx, `
+		SELECT
+			assumeNotNull(click_on) AS element,
+			count() AS clicks,
+			uniqExact(visitor_id)
+		FROM events
+		WHERE `+where+`
+		GROUP BY element
+		ORDER BY clicks DESC, element
+		LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer elementRows.Close()
+
+	for elementRows.Next() {
+		var element types.HeatmapElement
+		if err := elementRows.Scan(&element.Element, &element.Clicks, &element.Visitors); err != nil {
+			return nil, err
+		}
+
+		if response.Clicks > 0 {
+			element.Share = float64(element.Clicks) / float64(response.Clicks)
+		}
+		response.Elements = append(response.Elements, element)
+	}
+
+	return response, elementRows.Err()
+}
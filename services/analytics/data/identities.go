@@ -0,0 +1,112 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"zori/services/analytics/filters"
+)
+
+// MaxUserVisitors caps the visitor IDs listed for a user
+const MaxUserVisitors = 100
+
+// identitiesQuery returns a query with the user each visitor of the project of the scope is linked to, the
+// identify or alias of a visitor stored last wins and the client timestamp only breaks ties within a batch
+func identitiesQuery(scope *Scope) (string, []any) {
+	query := `
+		SELECT visitor_id, argMax(user_id, (server_timestamp_utc, identified_at)) AS user_id
+		FROM identities
+		WHERE organization_id = ? AND project_id = ?
+		GROUP BY visitor_id`
+
+	return query, []any{scope.OrganizationID, scope.ProjectID}
+}
+
+// personExpr identifies people after stitching, the user of identified visitors and the visitor itself otherwise.
+// It reads visitor_id and the user_id of identitiesQuery joined on visitor_id.
+const personExpr = "if(user_id != '', user_id, visitor_id)"
+
+// People returns the number of distinct people in scope, visitors linked to the same user count once
+func (a *AnalyticsData) People(ctx context.Context, scope *Scope) (uint64, error) {
+	where, args := scope.Where()
+	identities, identitiesArgs := identitiesQuery(scope)
+
+	var people uint64
+	err := a.db.QueryRow(ctx, `
+		SELECT uniqExact(`+personExpr+`)
+		FROM (SELECT DISTINCT visitor_id FROM events WHERE `+where+`) AS visitors
+		LEFT JOIN (`+identities+`) AS identities USING visitor_id`, append(args, identitiesArgs...)...).
+		Scan(&people)
+
+	return people, err
+}
+
+// UserFilter restricts the scope to the events of every visitor linked to the user
+func UserFilter(scope *Scope, userID string) *filters.Filter {
+	identities, args := identitiesQuery(scope)
+
+	return &filters.Filter{
+		SQL:  "visitor_id IN (SELECT visitor_id FROM (" + identities + ") WHERE user_id = ?)",
+		Args: append(args, userID),
+	}
+}
+
+// VisitorUserID returns the user the visitor is linked to, empty when the visitor was never identified
+func (a *AnalyticsData) VisitorUserID(ctx context.Context, scope *Scope, visitorID string) (string, error) {
+	identities, args := identitiesQuery(scope)
+
+	var userID string
+	err := a.db.QueryRow(ctx, `
+		SELECT any(user_id)
+		FROM (`+identities+`)
+		WHERE visitor_id = ?`, append(args, visitorID)...).
+		Scan(&userID)
+
+	return userID, err
+}
+
+// UserVisitorIDs returns the visitors linked to the user
+func (a *AnalyticsData) UserVisitorIDs(ctx context.Context, scope *Scope, userID string) ([]string, error) {
+	identities, args := identitiesQuery(scope)
+
+	rows, err := a.db.Query(ctx, `
+		SELECT visitor_id
+		FROM (`+identities+`)
+		WHERE user_id = ?
+		ORDER BY visitor_id
+		LIMIT ?`, append(args, userID, MaxUserVisitors)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	visitorIDs := []string{}
+	for rows.Next() {
+		var visitorID string
+		if err := rows.Scan(&visitorID); err != nil {
+			return nil, err
+		}
+		visitorIDs = append(visitorIDs, visitorID)
+	}
+
+	return visitorIDs, rows.Err()
+}
+
+// UserTraits returns the traits of the identify of the user stored last, nil when the user was never identified with traits
+func (a *AnalyticsData) UserTraits(ctx context.Context, scope *Scope, userID string) (map[string]any, error) {
+	var traitsJSON string
+	err := a.db.QueryRow(ctx, `
+		SELECT argMax(traits, (server_timestamp_utc, identified_at))
+		FROM user_traits
+		WHERE organization_id = ? AND project_id = ? AND user_id = ?`, scope.OrganizationID, scope.ProjectID, userID).
+		Scan(&traitsJSON)
+	if err != nil || traitsJSON == "" {
+		return nil, err
+	}
+
+	var traits map[string]any
+	if err := json.Unmarshal([]byte(traitsJSON), &traits); err != nil {
+		return nil, err
+	}
+
+	return traits, nil
+}
@@ -0,0 +1,149 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"zori/services/analytics/types"
+)
+
+// pathsStepsQuery returns a query with the steps of every session in scope, a step is a page view written as
+// page:<path> or a custom event written as event:<name>. Repeated steps, such as reloads, are collapsed.
+func pathsStepsQuery(scope *Scope) (string, []any) {
+	sessionizedEvents, args := sessionizedEventsQuery(scope)
+
+	query := `
+		SELECT arrayCompact(arrayMap(step -> step.2, arraySort(step -> step.1, groupArray((ts, node))))) AS steps
+		FROM (
+			SELECT
+				visitor_id,
+				session_index,
+				ts,
+				multiIf(
+					is_page_view, concat('` + types.PathNodePage + `:', page_path),
+					event_name IS NOT NULL, concat('` + types.PathNodeEvent + `:', assumeNotNull(event_name)),
+					''
+				) AS node
+			FROM (` + sessionizedEvents + `)
+		)
+		WHERE node != ''
+		GROUP BY visitor_id, session_index`
+
+	return query, args
+}
+
+// Paths returns the most common sequences of up to depth steps following the first occurrence of the node in a session,
+// or leading to its last occurrence when backward. Sequences are in chronological order and include the node.
+func (a *AnalyticsData) Paths(ctx context.Context, scope *Scope, node string, depth int, backward bool, limit int) (*types.PathsResponse, error) {
+	steps, args := pathsStepsQuery(scope)
+
+	pathExpr := "arraySlice(steps, indexOf(steps, ?), ?)"
+	if backward {
+		pathExpr = "arrayReverse(arraySlice(arrayReverse(steps), indexOf(arrayReverse(steps), ?), ?))"
+	}
+
+	response := &types.PathsResponse{
+		From:  scope.From,
+		To:    scope.To,
+		Depth: depth,
+		Paths: []types.PathSequence{},
+	}
+
+	err := a.db.QueryRow(ctx, `
+		SELECT count()
+		FROM (`+steps+`)
+		WHERE has(steps, ?)`, append(args, node)...).
+		Scan(&response.Sessions)
+	if err != nil {
+		return nil, err
+	}
+
+	queryArgs := append([]any{node, depth + 1}, args...)
+	queryArgs = append(queryArgs, node, limit)
+
+	rows, err := a.db.Query(ctx, `
+		SELECT `+pathExpr+` AS path, count() AS sessions
+		FROM (`+steps+`)
+		WHERE has(steps, ?)
+		GROUP BY path
+		ORDER BY sessions DESC, path
+		LIMIT ?`, queryArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			nodes    []string
+			sessions uint64
+		)
+		if err := rows.Scan(&nodes, &sessions); err != nil {
+			return nil, err
+		}
+
+		sequence := types.PathSequence{Sessions: sessions, Steps: make([]types.PathStep, len(nodes))}
+		for idx, node := range nodes {
+			sequence.Steps[idx] = ParsePathNode(node)
+		}
+		response.Paths = append(response.Paths, sequence)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	response.Nodes, response.Edges = PathsGraph(response.Paths, backward)
+
+	return response, nil
+}
+
+// PathNode returns the step node written the way pathsStepsQuery writes it
+func PathNode(nodeType string, value string) string {
+	return nodeType + ":" + value
+}
+
+// ParsePathNode splits a node written by pathsStepsQuery into its type and value
+func ParsePathNode(node string) types.PathStep {
+	nodeType, value, _ := strings.Cut(node, ":")
+	return types.PathStep{Type: nodeType, Value: value}
+}
+
+// PathsGraph merges the sequences into a Sankey graph. Nodes are unique per depth so the graph has no cycles, depth is
+// the distance from the chosen node: counting up after it going forward and counting down before it going backward.
+func PathsGraph(paths []types.PathSequence, backward bool) ([]types.PathNode, []types.PathEdge) {
+	nodes := []types.PathNode{}
+	edges := []types.PathEdge{}
+	nodeIndexes := make(map[string]int)
+	edgeIndexes := make(map[[2]string]int)
+
+	for _, path := range paths {
+		previousID := ""
+		for idx, step := range path.Steps {
+			depth := idx
+			if backward {
+				depth = idx - (len(path.Steps) - 1)
+			}
+
+			id := fmt.Sprintf("%d:%s:%s", depth, step.Type, step.Value)
+			if nodeIdx, ok := nodeIndexes[id]; ok {
+				nodes[nodeIdx].Sessions += path.Sessions
+			} else {
+				nodeIndexes[id] = len(nodes)
+				nodes = append(nodes, types.PathNode{ID: id, Type: step.Type, Value: step.Value, Depth: depth, Sessions: path.Sessions})
+			}
+
+			if previousID != "" {
+				key := [2]string{previousID, id}
+				if edgeIdx, ok := edgeIndexes[key]; ok {
+					edges[edgeIdx].Sessions += path.Sessions
+				} else {
+					edgeIndexes[key] = len(edges)
+					edges = append(edges, types.PathEdge{Source: previousID, Target: id, Sessions: path.Sessions})
+				}
+			}
+			previousID = id
+		}
+	}
+
+	return nodes, edges
+}
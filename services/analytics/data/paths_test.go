@@ -0,0 +1,86 @@
+package data
+
+import (
+	"testing"
+	"zori/services/analytics/types"
+)
+
+func pageStep(path string) types.PathStep {
+	return types.PathStep{Type: types.PathNodePage, Value: path}
+}
+
+func TestParsePathNode(t *testing.T) {
+	step := ParsePathNode(PathNode(types.PathNodeEvent, "signup:completed"))
+	if step.Type != types.PathNodeEvent || step.Value != "signup:completed" {
+		t.Errorf("Expected event step signup:completed, got %+v", step)
+	}
+}
+
+func TestPathsGraph(t *testing.T) {
+	paths := []types.PathSequence{
+		{Steps: []types.PathStep{pageStep("/"), pageStep("/pricing"), pageStep("/signup")}, Sessions: 30},
+		{Steps: []types.PathStep{pageStep("/"), pageStep("/pricing")}, Sessions: 20},
+		{Steps: []types.PathStep{pageStep("/"), pageStep("/blog"), pageStep("/")}, Sessions: 10},
+	}
+
+	nodes, edges := PathsGraph(paths, false)
+
+	expectedNodes := map[string]uint64{
+		"0:page:/":        60,
+		"1:page:/pricing": 50,
+		"2:page:/signup":  30,
+		"1:page:/blog":    10,
+		"2:page:/":        10,
+	}
+	if len(nodes) != len(expectedNodes) {
+		t.Fatalf("Expected %d nodes, got %d: %+v", len(expectedNodes), len(nodes), nodes)
+	}
+	for _, node := range nodes {
+		if expectedNodes[node.ID] != node.Sessions {
+			t.Errorf("Node %s: expected %d sessions, got %d", node.ID, expectedNodes[node.ID], node.Sessions)
+		}
+	}
+
+	expectedEdges := map[[2]string]uint64{
+		{"0:page:/", "1:page:/pricing"}:       50,
+		{"1:page:/pricing", "2:page:/signup"}: 30,
+		{"0:page:/", "1:page:/blog"}:          10,
+		{"1:page:/blog", "2:page:/"}:          10,
+	}
+	if len(edges) != len(expectedEdges) {
+		t.Fatalf("Expected %d edges, got %d: %+v", len(expectedEdges), len(edges), edges)
+	}
+	for _, edge := range edges {
+		if expectedEdges[[2]string{edge.Source, edge.Target}] != edge.Sessions {
+			t.Errorf("Edge %s -> %s: expected %d sessions, got %d", edge.Source, edge.Target, expectedEdges[[2]string{edge.Source, edge.Target}], edge.Sessions)
+		}
+	}
+}
+
+func TestPathsGraphBackward(t *testing.T) {
+	paths := []types.PathSequence{
+		{Steps: []types.PathStep{pageStep("/"), pageStep("/pricing"), pageStep("/signup")}, Sessions: 5},
+		{Steps: []types.PathStep{pageStep("/blog"), pageStep("/signup")}, Sessions: 3},
+	}
+
+	nodes, _ := PathsGraph(paths, true)
+
+	expectedDepths := map[string]int{
+		"-2:page:/":        -2,
+		"-1:page:/pricing": -1,
+		"0:page:/signup":   0,
+		"-1:page:/blog":    -1,
+	}
+	if len(nodes) != len(expectedDepths) {
+		t.Fatalf("Expected %d nodes, got %d: %+v", len(expectedDepths), len(nodes), nodes)
+	}
+	for _, node := range nodes {
+		depth, ok := expectedDepths[node.ID]
+		if !ok || depth != node.Depth {
+			t.Errorf("Unexpected node %+v", node)
+		}
+		if node.ID == "0:page:/signup" && node.Sessions != 8 {
+			t.Errorf("Expected 8 sessions to reach the chosen node, got %d", node.Sessions)
+		}
+	}
+}
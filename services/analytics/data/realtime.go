@@ -0,0 +1,56 @@
+package data
+
+import (
+	"context"
+	"zori/services/analytics/types"
+)
+
+// Realtime returns the visitors active within the scope and the pages they are on, the page a visitor is on
+// is the last page they viewed. The scope is expected to select events by server time.
+func (a *AnalyticsData) Realtime(ctx context.Context, scope *Scope, limit int) (*types.RealtimeResponse, error) {
+	where, args := scope.Where()
+
+	response := &types.RealtimeResponse{
+		Since: scope.From,
+		Pages: []types.RealtimePage{},
+	}
+
+	err := a.db.QueryRow(ctx, `
+		SELECT
+			uniqExact(visitor_id),
+			countIf(`+pageViewCondition+`),
+			count()
+		FROM events
+		WHERE `+where, args...).
+		Scan(&response.Visitors, &response.PageViews, &response.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := a.db.Query(ctx, `
+		SELECT page, count() AS visitors
+		FROM (
+			SELECT argMaxIf(page_path, server_timestamp_utc, `+pageViewCondition+`) AS page
+			FROM events
+			WHERE `+where+`
+			GROUP BY visitor_id
+		)
+		WHERE page != ''
+		GROUP BY page
+		ORDER BY visitors DESC, page
+		LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var page types.RealtimePage
+		if err := rows.Scan(&page.Page, &page.Visitors); err != nil {
+			return nil, err
+		}
+		response.Pages = append(response.Pages, page)
+	}
+
+	return response, rows.Err()
+}
@@ -0,0 +1,137 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"zori/services/analytics/types"
+)
+
+// MaxRetentionCohorts limits how many cohorts a single retention matrix may have
+const MaxRetentionCohorts = 104
+
+// Retention groups visitors into cohorts by the interval in which they were first seen for the project and counts
+// how many of them return in every following interval of the scope. Returning visits can be restricted to a
+// custom event. Filters apply to both the events placing visitors in cohorts and the returning events.
+func (a *AnalyticsData) Retention(ctx context.Context, scope *Scope, interval string, location *time.Location, eventName string) ([]types.RetentionCohort, error) {
+	buckets, err := Buckets(scope.From, scope.To, interval, location)
+	if err != nil {
+		return nil, err
+	}
+	if len(buckets) > MaxRetentionCohorts {
+		return nil, fmt.Errorf("the date range contains more than %d %s cohorts, use a shorter range", MaxRetentionCohorts, interval)
+	}
+
+	// visitors are placed in the cohort of their first event ever, not the first one within the scope. The first
+	// bucket may start before the scope, visitors first seen in it before the scope are left out of its cohort.
+	firstSeenScope := *scope
+	firstSeenScope.From = time.Unix(0, 0).UTC()
+	firstSeenWhere, firstSeenArgs := firstSeenScope.Where()
+
+	cohortsQuery := `
+		SELECT visitor_id, min(client_timestamp_utc) AS first_seen, ` + bucketExpr("first_seen", interval) + ` AS cohort
+		FROM events
+		WHERE ` + firstSeenWhere + `
+		GROUP BY visitor_id
+		HAVING first_seen >= ?`
+	cohortsArgs := append([]any{location.String(), location.String()}, firstSeenArgs...)
+	cohortsArgs = append(cohortsArgs, scope.From)
+
+	cohortSizes := make(map[int64]uint64, len(buckets))
+	sizeRows, err := a.db.Query(ctx, `
+		SELECT cohort, count()
+		FROM (`+cohortsQuery+`)
+		GROUP BY cohort`, cohortsArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer sizeRows.Close()
+
+	for sizeRows.Next() {
+		var (
+			cohort   uint32
+			visitors uint64
+		)
+		if err := sizeRows.Scan(&cohort, &visitors); err != nil {
+			return nil, err
+		}
+		cohortSizes[int64(cohort)] = visitors
+	}
+	if err := sizeRows.Err(); err != nil {
+		return nil, err
+	}
+
+	where, args := scope.Where()
+	activityArgs := append([]any{location.String(), location.String()}, args...)
+	if eventName != "" {
+		where += " AND " + customEventCondition + " AND event_name = ?"
+		activityArgs = append(activityArgs, eventName)
+	}
+
+	retainedRows, err := a.db.Query(ctx, `
+		SELECT cohort, period, uniqExact(visitor_id)
+		FROM (
+			SELECT visitor_id, `+bucketExpr("client_timestamp_utc", interval)+` AS period
+			FROM events
+			WHERE `+where+`
+		) AS activity
+		INNER JOIN (`+cohortsQuery+`) AS cohorts USING (visitor_id)
+		WHERE period > cohort
+		GROUP BY cohort, period`, append(activityArgs, cohortsArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer retainedRows.Close()
+
+	retained := make(map[[2]int64]uint64)
+	for retainedRows.Next() {
+		var (
+			cohort   uint32
+			period   uint32
+			visitors uint64
+		)
+		if err := retainedRows.Scan(&cohort, &period, &visitors); err != nil {
+			return nil, err
+		}
+		retained[[2]int64{int64(cohort), int64(period)}] = visitors
+	}
+	if err := retainedRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return RetentionMatrix(buckets, cohortSizes, retained), nil
+}
+
+// RetentionMatrix builds a cohort for every bucket from the cohort sizes and the visitors retained by cohort and period,
+// both keyed by the unix timestamp of the bucket starts. A cohort has a period for every bucket from its own one to the
+// last, the first period holds the whole cohort.
+func RetentionMatrix(buckets []time.Time, cohortSizes map[int64]uint64, retained map[[2]int64]uint64) []types.RetentionCohort {
+	cohorts := make([]types.RetentionCohort, len(buckets))
+
+	for cohortIdx, cohortStart := range buckets {
+		size := cohortSizes[cohortStart.Unix()]
+
+		cohort := types.RetentionCohort{
+			Cohort:   cohortStart,
+			Visitors: size,
+			Retained: make([]uint64, len(buckets)-cohortIdx),
+			Rates:    make([]float64, len(buckets)-cohortIdx),
+		}
+
+		for period := range cohort.Retained {
+			if period == 0 {
+				cohort.Retained[period] = size
+			} else {
+				cohort.Retained[period] = retained[[2]int64{cohortStart.Unix(), buckets[cohortIdx+period].Unix()}]
+			}
+
+			if size > 0 {
+				cohort.Rates[period] = float64(cohort.Retained[period]) / float64(size)
+			}
+		}
+
+		cohorts[cohortIdx] = cohort
+	}
+
+	return cohorts
+}
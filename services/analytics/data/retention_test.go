@@ -0,0 +1,51 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetentionMatrix(t *testing.T) {
+	week1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	week2 := week1.AddDate(0, 0, 7)
+	week3 := week1.AddDate(0, 0, 14)
+	buckets := []time.Time{week1, week2, week3}
+
+	cohortSizes := map[int64]uint64{week1.Unix(): 100, week2.Unix(): 50}
+	retained := map[[2]int64]uint64{
+		{week1.Unix(), week2.Unix()}: 40,
+		{week1.Unix(), week3.Unix()}: 25,
+		{week2.Unix(), week3.Unix()}: 10,
+	}
+
+	cohorts := RetentionMatrix(buckets, cohortSizes, retained)
+	if len(cohorts) != 3 {
+		t.Fatalf("Expected 3 cohorts, got %d", len(cohorts))
+	}
+
+	expected := []struct {
+		visitors uint64
+		retained []uint64
+		rates    []float64
+	}{
+		{100, []uint64{100, 40, 25}, []float64{1, 0.4, 0.25}},
+		{50, []uint64{50, 10}, []float64{1, 0.2}},
+		{0, []uint64{0}, []float64{0}},
+	}
+
+	for idx, cohort := range cohorts {
+		if !cohort.Cohort.Equal(buckets[idx]) {
+			t.Errorf("Cohort %d: expected start %s, got %s", idx, buckets[idx], cohort.Cohort)
+		}
+		if cohort.Visitors != expected[idx].visitors {
+			t.Errorf("Cohort %d: expected %d visitors, got %d", idx, expected[idx].visitors, cohort.Visitors)
+		}
+		if !reflect.DeepEqual(cohort.Retained, expected[idx].retained) {
+			t.Errorf("Cohort %d: expected retained %v, got %v", idx, expected[idx].retained, cohort.Retained)
+		}
+		if !reflect.DeepEqual(cohort.Rates, expected[idx].rates) {
+			t.Errorf("Cohort %d: expected rates %v, got %v", idx, expected[idx].rates, cohort.Rates)
+		}
+	}
+}
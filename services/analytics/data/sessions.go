@@ -0,0 +1,150 @@
+package data
+
+import (
+	"context"
+	"zori/services/analytics/types"
+)
+
+// sessionizedEventsQuery returns a query with the events in scope numbered by the session of their visitor in
+// session_index. Events of a visitor belong to the same session until the visitor is inactive for longer than the
+// session timeout of the project. Sessions are computed at query time, so a session running across the start of
+// the range is cut at the start, and filters select the events sessions are made of.
+func sessionizedEventsQuery(scope *Scope) (string, []any) {
+	where, args := scope.Where()
+
+	query := `
+		SELECT
+			*,
+			sum(is_new_session) OVER (PARTITION BY visitor_id ORDER BY ts ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) AS session_index
+		FROM (
+			SELECT
+				visitor_id,
+				client_timestamp_utc AS ts,
+				page_path,
+				event_name,
+				click_on,
+				channel,
+				source_name,
+				(` + pageViewCondition + `) AS is_page_view,
+				if(
+					row_number() OVER visitor_events = 1
+						OR dateDiff('second', lagInFrame(client_timestamp_utc) OVER visitor_events, client_timestamp_utc) > ?,
+					1, 0
+				) AS is_new_session
+			FROM events
+			WHERE ` + where + `
+			WINDOW visitor_events AS (PARTITION BY visitor_id ORDER BY client_timestamp_utc ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW)
+		)`
+
+	return query, append([]any{int64(scope.SessionTimeout.Seconds())}, args...)
+}
+
+// sessionAttributedEventsQuery returns a query with the sessionized events in scope carrying the value of the column
+// of the first event of their session in session_value, so every event is attributed to the session landing
+func sessionAttributedEventsQuery(scope *Scope, column string) (string, []any) {
+	sessionizedEvents, args := sessionizedEventsQuery(scope)
+
+	query := `
+		SELECT
+			*,
+			argMin(` + column + `, ts) OVER (PARTITION BY visitor_id, session_index) AS session_value
+		FROM (` + sessionizedEvents + `)`
+
+	return query, args
+}
+
+// sessionsQuery returns a query with one row per session of the events in scope
+func sessionsQuery(scope *Scope) (string, []any) {
+	sessionizedEvents, args := sessionizedEventsQuery(scope)
+
+	query := `
+		SELECT
+			visitor_id,
+			lower(hex(cityHash64(visitor_id, min(ts)))) AS session_id,
+			min(ts) AS started_at,
+			max(ts) AS ended_at,
+			countIf(is_page_view) AS page_views,
+			count() AS events,
+			argMinIf(page_path, ts, is_page_view) AS entry_page,
+			argMaxIf(page_path, ts, is_page_view) AS exit_page
+		FROM (` + sessionizedEvents + `)
+		GROUP BY visitor_id, session_index`
+
+	return query, args
+}
+
+// Sessions returns the sessions in scope, a session bounces when it has exactly one page view,
+// sessions without page views are not bounces.
+// Entry and exit pages are the first and last page viewed in a session, limit caps each of the lists.
+func (a *AnalyticsData) Sessions(ctx context.Context, scope *Scope, limit int) (*types.SessionsResponse, error) {
+	sessions, args := sessionsQuery(scope)
+
+	response := &types.SessionsResponse{
+		From:                  scope.From,
+		To:                    scope.To,
+		SessionTimeoutMinutes: int(scope.SessionTimeout.Minutes()),
+	}
+
+	var bounces uint64
+	err := a.db.QueryRow(ctx, `
+		SELECT
+			count(),
+			countIf(page_views = 1),
+			ifNotFinite(avg(dateDiff('second', started_at, ended_at)), 0),
+			ifNotFinite(avg(page_views), 0)
+		FROM (`+sessions+`)`, args...).
+		Scan(&response.Sessions, &bounces, &response.AvgDurationSeconds, &response.AvgPageViews)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Sessions > 0 {
+		response.BounceRate = float64(bounces) / float64(response.Sessions)
+	}
+
+	response.EntryPages, err = a.sessionPages(ctx, sessions, args, "entry_page", limit)
+	if err != nil {
+		return nil, err
+	}
+
+	response.ExitPages, err = a.sessionPages(ctx, sessions, args, "exit_page", limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// sessionPages groups the sessions by their entry or exit page, sessions without page views are left out
+func (a *AnalyticsData) sessionPages(ctx context.Context, sessions string, args []any, pageColumn string, limit int) ([]types.SessionPage, error) {
+	rows, err := a.db.Query(ctx, `
+		SELECT
+			`+pageColumn+` AS page,
+			count() AS sessions,
+			countIf(page_views = 1)
+		FROM (`+sessions+`)
+		WHERE page != ''
+		GROUP BY page
+		ORDER BY sessions DESC, page
+		LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pages := []types.SessionPage{}
+	for rows.Next() {
+		var (
+			page    types.SessionPage
+			bounces uint64
+		)
+		if err := rows.Scan(&page.Page, &page.Sessions, &bounces); err != nil {
+			return nil, err
+		}
+
+		page.BounceRate = float64(bounces) / float64(page.Sessions)
+		pages = append(pages, page)
+	}
+
+	return pages, rows.Err()
+}
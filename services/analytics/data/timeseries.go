@@ -0,0 +1,185 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"zori/services/analytics/types"
+)
+
+// MaxTimeseriesBuckets limits how many buckets a single time series may have
+const MaxTimeseriesBuckets = 2000
+
+var intervalSQL = map[string]string{
+	types.IntervalMinute: "INTERVAL 1 MINUTE",
+	types.IntervalHour:   "INTERVAL 1 HOUR",
+	types.IntervalDay:    "INTERVAL 1 DAY",
+	types.IntervalWeek:   "INTERVAL 1 WEEK",
+	types.IntervalMonth:  "INTERVAL 1 MONTH",
+}
+
+// Timeseries returns the traffic of every bucket of the interval in the given timezone, buckets without events are filled with zeros
+func (a *AnalyticsData) Timeseries(ctx context.Context, scope *Scope, interval string, location *time.Location) ([]types.TimeseriesPoint, error) {
+	buckets, err := Buckets(scope.From, scope.To, interval, location)
+	if err != nil {
+		return nil, err
+	}
+
+	where, args := scope.Where()
+	rows, err := a.db.Query(ctx, `
+		SELECT
+			`+bucketExpr("client_timestamp_utc", interval)+` AS bucket,
+			uniqExact(visitor_id),
+			countIf(`+pageViewCondition+`),
+			count()
+		FROM events
+		WHERE `+where+`
+		GROUP BY bucket`, append([]any{location.String(), location.String()}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pointsByBucket := make(map[int64]types.TimeseriesPoint)
+	for rows.Next() {
+		var (
+			bucket uint32
+			point  types.TimeseriesPoint
+		)
+		if err := rows.Scan(&bucket, &point.Visitors, &point.PageViews, &point.Events); err != nil {
+			return nil, err
+		}
+		pointsByBucket[int64(bucket)] = point
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	points := make([]types.TimeseriesPoint, len(buckets))
+	for idx, bucket := range buckets {
+		points[idx] = pointsByBucket[bucket.Unix()]
+		points[idx].Time = bucket
+	}
+
+	return points, nil
+}
+
+// AlignPrevious aligns the points of the previous period to the points of the current one by their offset from
+// the start of their period, counted in real time for minutes and hours and in calendar steps for longer intervals.
+// Buckets missing from the previous period are filled with zeros, so the result always has the length of points.
+func AlignPrevious(points []types.TimeseriesPoint, previous []types.TimeseriesPoint, interval string) []types.TimeseriesPoint {
+	if len(points) == 0 || len(previous) == 0 {
+		return nil
+	}
+
+	currentStart, previousStart := points[0].Time, previous[0].Time
+	previousByOffset := make(map[int]types.TimeseriesPoint, len(previous))
+	for _, point := range previous {
+		previousByOffset[bucketOffset(previousStart, point.Time, interval)] = point
+	}
+
+	aligned := make([]types.TimeseriesPoint, len(points))
+	for idx, point := range points {
+		offset := bucketOffset(currentStart, point.Time, interval)
+		previousPoint, ok := previousByOffset[offset]
+		if !ok {
+			previousPoint.Time = addBuckets(previousStart, offset, interval)
+		}
+		aligned[idx] = previousPoint
+	}
+
+	return aligned
+}
+
+// bucketOffset returns how many buckets of the interval the bucket lies after the start bucket
+func bucketOffset(start time.Time, bucket time.Time, interval string) int {
+	switch interval {
+	case types.IntervalMinute:
+		return int(bucket.Sub(start) / time.Minute)
+	case types.IntervalHour:
+		return int(bucket.Sub(start) / time.Hour)
+	case types.IntervalMonth:
+		return (bucket.Year()-start.Year())*12 + int(bucket.Month()) - int(start.Month())
+	}
+
+	// days are counted on the calendar, so days of 23 or 25 hours count as one
+	startDate := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	bucketDate := time.Date(bucket.Year(), bucket.Month(), bucket.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(bucketDate.Sub(startDate) / (24 * time.Hour))
+	if interval == types.IntervalWeek {
+		return days / 7
+	}
+	return days
+}
+
+// addBuckets returns the start of the bucket offset buckets of the interval after the start bucket
+func addBuckets(start time.Time, offset int, interval string) time.Time {
+	switch interval {
+	case types.IntervalMinute:
+		return start.Add(time.Duration(offset) * time.Minute)
+	case types.IntervalHour:
+		return start.Add(time.Duration(offset) * time.Hour)
+	case types.IntervalWeek:
+		return start.AddDate(0, 0, 7*offset)
+	case types.IntervalMonth:
+		return start.AddDate(0, offset, 0)
+	default:
+		return start.AddDate(0, 0, offset)
+	}
+}
+
+// bucketExpr returns the unix timestamp of the start of the bucket containing the timestamp expression,
+// the expression takes the timezone name twice as arguments
+func bucketExpr(timestampExpr string, interval string) string {
+	return fmt.Sprintf("toUnixTimestamp(toDateTime(toStartOfInterval(%s, %s, ?), ?))", timestampExpr, intervalSQL[interval])
+}
+
+// Buckets returns the start of every interval bucket overlapping [from, to) aligned in the given timezone
+func Buckets(from time.Time, to time.Time, interval string, location *time.Location) ([]time.Time, error) {
+	if _, ok := intervalSQL[interval]; !ok {
+		return nil, fmt.Errorf("unsupported interval %q", interval)
+	}
+
+	var buckets []time.Time
+	for bucket := truncateToInterval(from.In(location), interval); bucket.Before(to); bucket = nextBucket(bucket, interval) {
+		if len(buckets) == MaxTimeseriesBuckets {
+			return nil, fmt.Errorf("the date range contains more than %d %s buckets, use a larger interval", MaxTimeseriesBuckets, interval)
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, nil
+}
+
+func truncateToInterval(t time.Time, interval string) time.Time {
+	location := t.Location()
+	switch interval {
+	case types.IntervalMinute:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, location)
+	case types.IntervalHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, location)
+	case types.IntervalWeek:
+		// weeks start on Monday, the same way ClickHouse aligns them
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, location)
+	case types.IntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, location)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location)
+	}
+}
+
+func nextBucket(t time.Time, interval string) time.Time {
+	switch interval {
+	case types.IntervalMinute:
+		return t.Add(time.Minute)
+	case types.IntervalHour:
+		return t.Add(time.Hour)
+	case types.IntervalWeek:
+		return t.AddDate(0, 0, 7)
+	case types.IntervalMonth:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
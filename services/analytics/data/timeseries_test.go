@@ -0,0 +1,120 @@
+package data
+
+import (
+	"testing"
+	"time"
+	"zori/services/analytics/types"
+)
+
+func TestBuckets(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("Failed to load timezone: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		from          time.Time
+		to            time.Time
+		interval      string
+		location      *time.Location
+		expectedCount int
+		expectedFirst time.Time
+	}{
+		{
+			name:          "days in UTC",
+			from:          time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			to:            time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC),
+			interval:      types.IntervalDay,
+			location:      time.UTC,
+			expectedCount: 7,
+			expectedFirst: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "days aligned to timezone",
+			from:          time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			to:            time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+			interval:      types.IntervalDay,
+			location:      berlin,
+			expectedCount: 3,
+			expectedFirst: time.Date(2024, 1, 1, 0, 0, 0, 0, berlin),
+		},
+		{
+			name:          "hours across daylight saving change",
+			from:          time.Date(2024, 3, 30, 23, 0, 0, 0, time.UTC),
+			to:            time.Date(2024, 3, 31, 23, 0, 0, 0, time.UTC),
+			interval:      types.IntervalHour,
+			location:      berlin,
+			expectedCount: 24,
+			expectedFirst: time.Date(2024, 3, 31, 0, 0, 0, 0, berlin),
+		},
+		{
+			name:          "weeks start on monday",
+			from:          time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+			to:            time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC),
+			interval:      types.IntervalWeek,
+			location:      time.UTC,
+			expectedCount: 3,
+			expectedFirst: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "months",
+			from:          time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			to:            time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+			interval:      types.IntervalMonth,
+			location:      time.UTC,
+			expectedCount: 3,
+			expectedFirst: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buckets, err := Buckets(test.from, test.to, test.interval, test.location)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if len(buckets) != test.expectedCount {
+				t.Fatalf("Expected %d buckets, got %d", test.expectedCount, len(buckets))
+			}
+
+			if !buckets[0].Equal(test.expectedFirst) {
+				t.Errorf("Expected first bucket %s, got %s", test.expectedFirst, buckets[0])
+			}
+		})
+	}
+
+	t.Run("too many buckets", func(t *testing.T) {
+		_, err := Buckets(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), types.IntervalMinute, time.UTC)
+		if err == nil {
+			t.Fatal("Expected error for too many buckets, got none")
+		}
+	})
+}
+
+func TestAlignPrevious(t *testing.T) {
+	pointsOf := func(buckets []time.Time) []types.TimeseriesPoint {
+		points := make([]types.TimeseriesPoint, len(buckets))
+		for idx, bucket := range buckets {
+			points[idx] = types.TimeseriesPoint{Time: bucket, Events: uint64(idx + 1)}
+		}
+		return points
+	}
+
+	t.Run("months of different periods", func(t *testing.T) {
+		current, _ := Buckets(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), types.IntervalMonth, time.UTC)
+		previous, _ := Buckets(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), types.IntervalMonth, time.UTC)
+
+		aligned := AlignPrevious(pointsOf(current), pointsOf(previous), types.IntervalMonth)
+		if len(aligned) != len(current) {
+			t.Fatalf("Expected %d points, got %d", len(current), len(aligned))
+		}
+		if aligned[0].Events != 1 || !aligned[0].Time.Equal(previous[0]) {
+			t.Errorf("Expected the first month to be aligned, got %+v", aligned[0])
+		}
+		if aligned[1].Events != 0 || !aligned[1].Time.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("Expected a zero filled second month, got %+v", aligned[1])
+		}
+	})
+}
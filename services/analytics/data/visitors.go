@@ -0,0 +1,218 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"zori/services/analytics/filters"
+	"zori/services/analytics/types"
+)
+
+// Caps on the lists of a visitor profile, the timeline is paginated instead
+const (
+	MaxVisitorSessions  = 100
+	MaxVisitorDevices   = 20
+	MaxVisitorLocations = 20
+)
+
+// VisitorFilter restricts a scope to the events of a single visitor
+func VisitorFilter(visitorID string) *filters.Filter {
+	return &filters.Filter{SQL: "visitor_id = ?", Args: []any{visitorID}}
+}
+
+// Visitor returns the profile of the visitor or user the scope is restricted to along with a page of their timeline.
+// sql.ErrNoRows is returned when there are no events in scope.
+func (a *AnalyticsData) Visitor(ctx context.Context, scope *Scope, limit int, offset int) (*types.VisitorResponse, error) {
+	where, args := scope.Where()
+
+	response := &types.VisitorResponse{
+		Sessions:  []types.VisitorSession{},
+		Devices:   []types.VisitorDevice{},
+		Locations: []types.VisitorLocation{},
+		Timeline:  []types.VisitorEvent{},
+	}
+
+	err := a.db.QueryRow(ctx, `
+		SELECT
+			count(),
+			countIf(`+pageViewCondition+`),
+			min(client_timestamp_utc),
+			max(client_timestamp_utc)
+		FROM events
+		WHERE `+where, args...).
+		Scan(&response.Events, &response.PageViews, &response.FirstSeen, &response.LastSeen)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Events == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	if err := a.visitorSessions(ctx, scope, response); err != nil {
+		return nil, err
+	}
+
+	if response.Devices, err = a.visitorDevices(ctx, where, args); err != nil {
+		return nil, err
+	}
+
+	if response.Locations, err = a.visitorLocations(ctx, where, args); err != nil {
+		return nil, err
+	}
+
+	if response.Timeline, err = a.visitorTimeline(ctx, where, args, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// visitorSessions sets the most recent sessions of the visitor and the number of all of their sessions
+func (a *AnalyticsData) visitorSessions(ctx context.Context, scope *Scope, response *types.VisitorResponse) error {
+	sessions, args := sessionsQuery(scope)
+
+	rows, err := a.db.Query(ctx, `
+		SELECT
+			session_id,
+			started_at,
+			ended_at,
+			page_views,
+			events,
+			entry_page,
+			exit_page,
+			count() OVER () AS total
+		FROM (`+sessions+`)
+		ORDER BY started_at DESC
+		LIMIT ?`, append(args, MaxVisitorSessions)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var session types.VisitorSession
+		if err := rows.Scan(
+			&session.SessionID,
+			&session.StartedAt,
+			&session.EndedAt,
+			&session.PageViews,
+			&session.Events,
+			&session.EntryPage,
+			&session.ExitPage,
+			&response.SessionCount,
+		); err != nil {
+			return err
+		}
+		response.Sessions = append(response.Sessions, session)
+	}
+
+	return rows.Err()
+}
+
+// visitorDevices returns the devices of the visitor, most recently used first
+func (a *AnalyticsData) visitorDevices(ctx context.Context, where string, args []any) ([]types.VisitorDevice, error) {
+	rows, err := a.db.Query(ctx, `
+		SELECT
+			ifNull(browser_name, '') AS browser,
+			ifNull(os_name, '') AS os,
+			ifNull(device_type, '') AS device,
+			count(),
+			min(client_timestamp_utc),
+			max(client_timestamp_utc) AS last_seen
+		FROM events
+		WHERE `+where+`
+		GROUP BY browser, os, device
+		ORDER BY last_seen DESC
+		LIMIT ?`, append(args, MaxVisitorDevices)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := []types.VisitorDevice{}
+	for rows.Next() {
+		var device types.VisitorDevice
+		if err := rows.Scan(&device.Browser, &device.OS, &device.DeviceType, &device.Events, &device.FirstSeen, &device.LastSeen); err != nil {
+			return nil, err
+		}
+		devices = append(devices, device)
+	}
+
+	return devices, rows.Err()
+}
+
+// visitorLocations returns the locations of the visitor, most recently seen first
+func (a *AnalyticsData) visitorLocations(ctx context.Context, where string, args []any) ([]types.VisitorLocation, error) {
+	rows, err := a.db.Query(ctx, `
+		SELECT
+			`+Dimensions["country"]+` AS country,
+			`+Dimensions["city"]+` AS city,
+			count(),
+			min(client_timestamp_utc),
+			max(client_timestamp_utc) AS last_seen
+		FROM events
+		WHERE `+where+`
+		GROUP BY country, city
+		ORDER BY last_seen DESC
+		LIMIT ?`, append(args, MaxVisitorLocations)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locations := []types.VisitorLocation{}
+	for rows.Next() {
+		var location types.VisitorLocation
+		if err := rows.Scan(&location.Country, &location.City, &location.Events, &location.FirstSeen, &location.LastSeen); err != nil {
+			return nil, err
+		}
+		locations = append(locations, location)
+	}
+
+	return locations, rows.Err()
+}
+
+// visitorTimeline returns a page of the events of the visitor in chronological order,
+// event types match the funnel step types
+func (a *AnalyticsData) visitorTimeline(ctx context.Context, where string, args []any, limit int, offset int) ([]types.VisitorEvent, error) {
+	rows, err := a.db.Query(ctx, `
+		SELECT
+			client_timestamp_utc,
+			toString(client_generated_event_id),
+			multiIf(`+customEventCondition+`, 'event', `+clickCondition+`, 'click', 'page_view'),
+			event_name,
+			page_path,
+			page_url,
+			referrer_url,
+			click_on,
+			custom_properties_map
+		FROM events
+		WHERE `+where+`
+		ORDER BY client_timestamp_utc, client_generated_event_id
+		LIMIT ? OFFSET ?`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	timeline := []types.VisitorEvent{}
+	for rows.Next() {
+		var event types.VisitorEvent
+		if err := rows.Scan(
+			&event.Timestamp,
+			&event.EventID,
+			&event.Type,
+			&event.EventName,
+			&event.PagePath,
+			&event.PageURL,
+			&event.Referrer,
+			&event.ClickOn,
+			&event.CustomProperties,
+		); err != nil {
+			return nil, err
+		}
+		timeline = append(timeline, event)
+	}
+
+	return timeline, rows.Err()
+}
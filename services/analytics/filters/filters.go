@@ -0,0 +1,334 @@
+// Package filters implements the filter expression language accepted by the analytics endpoints,
+// for example: country = DE and page_path starts with /blog and utm_source != google.
+//
+// Conditions compare a field with a value and are combined with and, or, not and parentheses.
+// Fields are the report dimensions or custom properties written as props.<key>. Supported operators:
+//
+//	=, !=                      equal / not equal
+//	contains, not contains     substring match
+//	starts with, ends with     prefix / suffix match, also written starts_with and ends_with
+//	in (a, b), not in (a, b)   membership in a list of values
+//	is set, is not set         the field has a value
+//	>, >=, <, <=               numeric comparison, custom properties only
+//
+// Values are either quoted with single or double quotes or written as a single unquoted word.
+// Expressions compile to parameterized ClickHouse SQL, values never end up in the query text.
+package filters
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"zori/internal/utils"
+	ingestionTypes "zori/services/ingestion/types"
+)
+
+// Limits keeping compiled filters small
+const (
+	MaxFilterLength = 2048
+	MaxConditions   = 20
+	MaxInValues     = 100
+)
+
+// Custom properties are read from the flattened properties map of the events table
+const (
+	propertyPrefix    = "props."
+	propertiesMapExpr = "custom_properties_map"
+)
+
+// Filter is a compiled filter expression, SQL is a boolean condition with ? placeholders for Args
+type Filter struct {
+	SQL  string
+	Args []any
+}
+
+// Parse validates the expression and compiles it to SQL, fields maps the field names usable in the
+// expression to the String expressions reading them. An empty expression returns a nil filter.
+// Errors are returned as *utils.ValidationError on the filter field.
+func Parse(input string, fields map[string]string) (*Filter, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, nil
+	}
+
+	if len(input) > MaxFilterLength {
+		return nil, newFilterError("filter must be no more than %d characters long", MaxFilterLength)
+	}
+
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, newFilterError("%s", err.Error())
+	}
+
+	p := &parser{tokens: tokens, fields: fields}
+
+	sql, args, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, newFilterError("unexpected %s at position %d", tok, tok.pos)
+	}
+
+	return &Filter{SQL: sql, Args: args}, nil
+}
+
+func newFilterError(format string, args ...any) error {
+	return &utils.ValidationError{Errors: map[string]string{"filter": fmt.Sprintf(format, args...)}}
+}
+
+type parser struct {
+	tokens     []token
+	pos        int
+	fields     map[string]string
+	conditions int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// parseOr parses conditions joined with or, and binds tighter than or
+func (p *parser) parseOr() (string, []any, error) {
+	return p.parseJoined("or", "OR", p.parseAnd)
+}
+
+func (p *parser) parseAnd() (string, []any, error) {
+	return p.parseJoined("and", "AND", p.parseUnary)
+}
+
+func (p *parser) parseJoined(keyword string, sqlOperator string, parseOperand func() (string, []any, error)) (string, []any, error) {
+	sql, args, err := parseOperand()
+	if err != nil {
+		return "", nil, err
+	}
+
+	operands := []string{sql}
+	for p.peek().is(keyword) {
+		p.next()
+
+		sql, operandArgs, err := parseOperand()
+		if err != nil {
+			return "", nil, err
+		}
+		operands = append(operands, sql)
+		args = append(args, operandArgs...)
+	}
+
+	if len(operands) == 1 {
+		return operands[0], args, nil
+	}
+
+	return "(" + strings.Join(operands, " "+sqlOperator+" ") + ")", args, nil
+}
+
+func (p *parser) parseUnary() (string, []any, error) {
+	tok := p.peek()
+
+	switch {
+	case tok.is("not"):
+		p.next()
+		sql, args, err := p.parseUnary()
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT " + sql, args, nil
+	case tok.kind == tokenLeftParen:
+		p.next()
+		sql, args, err := p.parseOr()
+		if err != nil {
+			return "", nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRightParen {
+			return "", nil, newFilterError("expected \")\" at position %d, got %s", closing.pos, closing)
+		}
+		return sql, args, nil
+	default:
+		return p.parseCondition()
+	}
+}
+
+// field is a resolved field of a condition
+type field struct {
+	name string
+	expr string
+	args []any
+	// property fields read a custom property which may be compared as a number
+	property bool
+}
+
+func (p *parser) parseField() (*field, error) {
+	tok := p.next()
+	if tok.kind != tokenWord {
+		return nil, newFilterError("expected a field at position %d, got %s", tok.pos, tok)
+	}
+
+	if key, ok := strings.CutPrefix(tok.text, propertyPrefix); ok {
+		if key == "" || len(key) > ingestionTypes.MaxCustomPropertyKeyLength {
+			return nil, newFilterError("invalid custom property %q at position %d", tok.text, tok.pos)
+		}
+		return &field{name: tok.text, expr: propertiesMapExpr + "[?]", args: []any{key}, property: true}, nil
+	}
+
+	expr, ok := p.fields[tok.text]
+	if !ok {
+		return nil, newFilterError("unknown field %q at position %d", tok.text, tok.pos)
+	}
+
+	return &field{name: tok.text, expr: expr}, nil
+}
+
+func (p *parser) parseCondition() (string, []any, error) {
+	p.conditions++
+	if p.conditions > MaxConditions {
+		return "", nil, newFilterError("filter may contain at most %d conditions", MaxConditions)
+	}
+
+	f, err := p.parseField()
+	if err != nil {
+		return "", nil, err
+	}
+
+	opToken := p.next()
+	operator, err := p.parseOperator(opToken)
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch operator {
+	case "is set", "is not set":
+		sql, args := f.expr+" != ''", f.args
+		if f.property {
+			sql, args = "mapContains("+propertiesMapExpr+", ?)", f.args
+		}
+		if operator == "is not set" {
+			sql = "NOT " + sql
+		}
+		return sql, args, nil
+	case "in", "not in":
+		values, err := p.parseList()
+		if err != nil {
+			return "", nil, err
+		}
+		sql := "has(?, " + f.expr + ")"
+		if operator == "not in" {
+			sql = "NOT " + sql
+		}
+		return sql, append([]any{values}, f.args...), nil
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch operator {
+	case "=", "!=":
+		return f.expr + " " + operator + " ?", append(f.args, value), nil
+	case "contains":
+		return "position(" + f.expr + ", ?) > 0", append(f.args, value), nil
+	case "not contains":
+		return "position(" + f.expr + ", ?) = 0", append(f.args, value), nil
+	case "starts with":
+		return "startsWith(" + f.expr + ", ?)", append(f.args, value), nil
+	case "ends with":
+		return "endsWith(" + f.expr + ", ?)", append(f.args, value), nil
+	}
+
+	// only numeric comparisons are left
+	if !f.property {
+		return "", nil, newFilterError("operator %s at position %d needs a numeric custom property, %s is text", operator, opToken.pos, f.name)
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return "", nil, newFilterError("operator %s at position %d needs a number, got %q", operator, opToken.pos, value)
+	}
+
+	return "toFloat64OrNull(" + f.expr + ") " + operator + " ?", append(f.args, number), nil
+}
+
+// parseOperator reads the operator starting with tok and returns its canonical spelling
+func (p *parser) parseOperator(tok token) (string, error) {
+	if tok.kind == tokenOperator {
+		return tok.text, nil
+	}
+
+	switch {
+	case tok.is("contains"), tok.is("in"):
+		return strings.ToLower(tok.text), nil
+	case tok.is("starts_with"):
+		return "starts with", nil
+	case tok.is("ends_with"):
+		return "ends with", nil
+	case tok.is("starts"), tok.is("ends"):
+		if with := p.next(); !with.is("with") {
+			return "", newFilterError("expected \"with\" at position %d, got %s", with.pos, with)
+		}
+		return strings.ToLower(tok.text) + " with", nil
+	case tok.is("not"):
+		negated := p.next()
+		if !negated.is("contains") && !negated.is("in") {
+			return "", newFilterError("expected \"contains\" or \"in\" at position %d, got %s", negated.pos, negated)
+		}
+		return "not " + strings.ToLower(negated.text), nil
+	case tok.is("is"):
+		operator := "is set"
+		set := p.next()
+		if set.is("not") {
+			operator = "is not set"
+			set = p.next()
+		}
+		if !set.is("set") {
+			return "", newFilterError("expected \"set\" at position %d, got %s", set.pos, set)
+		}
+		return operator, nil
+	}
+
+	return "", newFilterError("expected an operator at position %d, got %s", tok.pos, tok)
+}
+
+func (p *parser) parseValue() (string, error) {
+	tok := p.next()
+	if tok.kind != tokenWord && tok.kind != tokenString {
+		return "", newFilterError("expected a value at position %d, got %s", tok.pos, tok)
+	}
+
+	return tok.text, nil
+}
+
+// parseList parses a parenthesized, comma separated list of values
+func (p *parser) parseList() ([]string, error) {
+	if tok := p.next(); tok.kind != tokenLeftParen {
+		return nil, newFilterError("expected \"(\" at position %d, got %s", tok.pos, tok)
+	}
+
+	var values []string
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		if len(values) > MaxInValues {
+			return nil, newFilterError("lists may contain at most %d values", MaxInValues)
+		}
+
+		tok := p.next()
+		if tok.kind == tokenRightParen {
+			return values, nil
+		}
+		if tok.kind != tokenComma {
+			return nil, newFilterError("expected \",\" or \")\" at position %d, got %s", tok.pos, tok)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package filters
+
+import (
+	"reflect"
+	"testing"
+	"zori/internal/utils"
+)
+
+var testFields = map[string]string{
+	"country":    "ifNull(toString(location_country_iso), '')",
+	"page_path":  "page_path",
+	"utm_source": "utm_source",
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		expectedSQL  string
+		expectedArgs []any
+	}{
+		{
+			"equality",
+			"country = DE",
+			"ifNull(toString(location_country_iso), '') = ?",
+			[]any{"DE"},
+		},
+		{
+			"and binds tighter than or",
+			"country = DE and page_path starts with /blog or utm_source != 'google'",
+			"((ifNull(toString(location_country_iso), '') = ? AND startsWith(page_path, ?)) OR utm_source != ?)",
+			[]any{"DE", "/blog", "google"},
+		},
+		{
+			"parentheses and not",
+			`not (page_path contains "pricing" OR page_path ends_with .pdf)`,
+			"NOT (position(page_path, ?) > 0 OR endsWith(page_path, ?))",
+			[]any{"pricing", ".pdf"},
+		},
+		{
+			"in list",
+			"country not in (DE, 'FR', US)",
+			"NOT has(?, ifNull(toString(location_country_iso), ''))",
+			[]any{[]string{"DE", "FR", "US"}},
+		},
+		{
+			"custom property",
+			"props.plan = 'pro plan' and props.cart.total >= 9.5",
+			"(custom_properties_map[?] = ? AND toFloat64OrNull(custom_properties_map[?]) >= ?)",
+			[]any{"plan", "pro plan", "cart.total", 9.5},
+		},
+		{
+			"is set",
+			"props.plan is not set and utm_source is set",
+			"(NOT mapContains(custom_properties_map, ?) AND utm_source != '')",
+			[]any{"plan"},
+		},
+		{
+			"escaped quote",
+			`page_path = 'it\'s'`,
+			"page_path = ?",
+			[]any{"it's"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := Parse(test.input, testFields)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if filter.SQL != test.expectedSQL {
+				t.Errorf("Expected SQL %q, got %q", test.expectedSQL, filter.SQL)
+			}
+			if !reflect.DeepEqual(filter.Args, test.expectedArgs) {
+				t.Errorf("Expected args %#v, got %#v", test.expectedArgs, filter.Args)
+			}
+		})
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	filter, err := Parse("  ", testFields)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if filter != nil {
+		t.Errorf("Expected no filter, got %+v", filter)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"unknown field", "city = Berlin"},
+		{"missing operator", "country DE"},
+		{"missing value", "country ="},
+		{"unterminated string", "country = 'DE"},
+		{"unbalanced parentheses", "(country = DE"},
+		{"trailing tokens", "country = DE US"},
+		{"numeric comparison on text field", "page_path > 3"},
+		{"numeric comparison with text", "props.total > lots"},
+		{"empty property key", "props. = 1"},
+		{"starts without with", "page_path starts /blog"},
+		{"bang without equals", "country ! DE"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse(test.input, testFields)
+			if err == nil {
+				t.Fatal("Expected error, got none")
+			}
+
+			validationErr, ok := err.(*utils.ValidationError)
+			if !ok {
+				t.Fatalf("Expected *utils.ValidationError, got %T", err)
+			}
+			if validationErr.Errors["filter"] == "" {
+				t.Errorf("Expected an error on the filter field, got %v", validationErr.Errors)
+			}
+		})
+	}
+}
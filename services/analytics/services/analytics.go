@@ -0,0 +1,539 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"zori/internal/ctx"
+	"zori/internal/natsstream"
+	"zori/internal/storage/postgres/models"
+	"zori/internal/utils"
+	"zori/services/analytics/data"
+	"zori/services/analytics/filters"
+	"zori/services/analytics/types"
+	projectsServices "zori/services/projects/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+type AnalyticsService struct {
+	data           *data.AnalyticsData
+	funnelData     *data.FunnelData
+	projectService *projectsServices.ProjectService
+	goalService    *projectsServices.GoalService
+	natsStream     *natsstream.Stream
+}
+
+func NewAnalyticsService(
+	data *data.AnalyticsData,
+	funnelData *data.FunnelData,
+	projectService *projectsServices.ProjectService,
+	goalService *projectsServices.GoalService,
+	natsStream *natsstream.Stream,
+) *AnalyticsService {
+	return &AnalyticsService{
+		data:           data,
+		funnelData:     funnelData,
+		projectService: projectService,
+		goalService:    goalService,
+		natsStream:     natsStream,
+	}
+}
+
+// @Summary Get project overview
+// @Description Get visitors, people, page views and events of a project over a date range, people count visitors linked to the same user once
+// @Tags Analytics
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param project_id path string true "Project ID"
+// @Param from query string false "Start of the range, RFC3339 or YYYY-MM-DD (defaults to 30 days before to)"
+// @Param to query string false "End of the range (exclusive), RFC3339 or YYYY-MM-DD (defaults to now)"
+// @Param filter query string false "Filter expression, e.g. country = DE and page_path starts with /blog"
+// @Success 200 {object} types.OverviewResponse "Project overview"
+// @Failure 400 {object} map[string]interface{} "Invalid request or validation failed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/analytics/{project_id}/overview [get]
+func (s *AnalyticsService) Overview(c *ctx.Ctx) (*types.OverviewResponse, error) {
+	var req types.OverviewRequest
+	if err := c.Echo.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request parameters")
+	}
+
+	scope, err := s.reportScope(c, &req.ReportRequest, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+
+	overview, err := s.data.Overview(c, scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get overview: %w", err)
+	}
+
+	return overview, nil
+}
+
+// @Summary Get project time series
+// @Description Get visitors, page views and events of a project grouped by interval in the requested timezone
+// @Tags Analytics
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param project_id path string true "Project ID"
+// @Param from query string false "Start of the range, RFC3339 or YYYY-MM-DD (defaults to 30 days before to)"
+// @Param to query string false "End of the range (exclusive), RFC3339 or YYYY-MM-DD (defaults to now)"
+// @Param interval query string false "Bucket size: minute, hour, day, week or month (defaults to day)"
+// @Param timezone query string false "IANA timezone buckets are aligned to (defaults to UTC)"
+// @Param compare query bool false "Include the same series for the previous period"
+// @Param filter query string false "Filter expression, e.g. country = DE and page_path starts with /blog"
+// @Success 200 {object} types.TimeseriesResponse "Project time series"
+// @Failure 400 {object} map[string]interface{} "Invalid request or validation failed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/analytics/{project_id}/timeseries [get]
+func (s *AnalyticsService) Timeseries(c *ctx.Ctx) (*types.TimeseriesResponse, error) {
+	var req types.TimeseriesRequest
+	if err := c.Echo.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request parameters")
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if req.Interval == "" {
+		req.Interval = types.IntervalDay
+	}
+
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+
+	location, err := time.LoadLocation(req.Timezone)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "timezone must be a valid IANA timezone")
+	}
+
+	scope, err := s.reportScope(c, &req.ReportRequest, location)
+	if err != nil {
+		return nil, err
+	}
+
+	// bucket count is checked upfront so a too fine interval is reported as a bad request
+	if _, err := data.Buckets(scope.From, scope.To, req.Interval, location); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	points, err := s.data.Timeseries(c, scope, req.Interval, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time series: %w", err)
+	}
+
+	response := &types.TimeseriesResponse{
+		From:     scope.From,
+		To:       scope.To,
+		Interval: req.Interval,
+		Timezone: location.String(),
+		Points:   points,
+	}
+
+	if req.Compare {
+		previousScope := *scope
+		previousScope.From = scope.From.Add(-scope.To.Sub(scope.From))
+		previousScope.To = scope.From
+
+		previous, err := s.data.Timeseries(c, &previousScope, req.Interval, location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get previous period time series: %w", err)
+		}
+		response.Previous = data.AlignPrevious(points, previous, req.Interval)
+	}
+
+	return response, nil
+}
+
+// @Summary Get dimension breakdown
+// @Description Get the top values of a dimension with their visitors, views and share of all visitors, channel and source are attributed to whole sessions by the landing event while filters on them match every event
+// @Tags Analytics
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param project_id path string true "Project ID"
+// @Param dimension query string true "Dimension: page_path, host, referrer_domain, country, region, city, timezone, isp, browser, os, device_type, event_name, utm_source, utm_medium, utm_campaign, channel or source"
+// @Param from query string false "Start of the range, RFC3339 or YYYY-MM-DD (defaults to 30 days before to)"
+// @Param to query string false "End of the range (exclusive), RFC3339 or YYYY-MM-DD (defaults to now)"
+// @Param limit query int false "Values per page, at most 100 (defaults to 10)"
+// @Param page query int false "Page number starting at 1"
+// @Param filter query string false "Filter expression, e.g. country = DE and page_path starts with /blog"
+// @Success 200 {object} types.BreakdownResponse "Dimension breakdown"
+// @Failure 400 {object} map[string]interface{} "Invalid request or validation failed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/analytics/{project_id}/breakdown [get]
+func (s *AnalyticsService) Breakdown(c *ctx.Ctx) (*types.BreakdownResponse, error) {
+	var req types.BreakdownRequest
+	if err := c.Echo.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request parameters")
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if _, ok := data.Dimensions[req.Dimension]; !ok {
+		return nil, echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("dimension must be one of: %s", strings.Join(data.DimensionNames(), ", ")))
+	}
+
+	if req.Limit == 0 {
+		req.Limit = types.DefaultBreakdownLimit
+	}
+
+	if req.Page == 0 {
+		req.Page = 1
+	}
+
+	scope, err := s.reportScope(c, &req.ReportRequest, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+
+	breakdown, err := s.data.Breakdown(c, scope, req.Dimension, req.Limit, (req.Page-1)*req.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get breakdown: %w", err)
+	}
+
+	breakdown.Page = req.Page
+	breakdown.Limit = req.Limit
+
+	return breakdown, nil
+}
+
+// @Summary Get sessions
+// @Description Get the sessions count, bounce rate, average duration and top entry and exit pages. Events of a visitor belong to the same session until the visitor is inactive for longer than the session timeout of the project.
+// @Tags Analytics
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param project_id path string true "Project ID"
+// @Param from query string false "Start of the range, RFC3339 or YYYY-MM-DD (defaults to 30 days before to)"
+// @Param to query string false "End of the range (exclusive), RFC3339 or YYYY-MM-DD (defaults to now)"
+// @Param limit query int false "Entry and exit pages to return, at most 100 (defaults to 10)"
+// @Param filter query string false "Filter expression, e.g. country = DE and page_path starts with /blog"
+// @Success 200 {object} types.SessionsResponse "Sessions"
+// @Failure 400 {object} map[string]interface{} "Invalid request or validation failed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/analytics/{project_id}/sessions [get]
+func (s *AnalyticsService) Sessions(c *ctx.Ctx) (*types.SessionsResponse, error) {
+	var req types.SessionsRequest
+	if err := c.Echo.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request parameters")
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if req.Limit == 0 {
+		req.Limit = types.DefaultSessionPagesLimit
+	}
+
+	scope, err := s.reportScope(c, &req.ReportRequest, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.data.Sessions(c, scope, req.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// @Summary Get click heatmap
+// @Description Get the clicks on a page binned into square cells and its most clicked elements. Only clicks sent with a page position and viewport width are placed, page positions are normalized by the viewport width and a cell spans 1/bins of the page width in both directions.
+// @Tags Analytics
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param project_id path string true "Project ID"
+// @Param page_path query string true "Path of the page"
+// @Param from query string false "Start of the range, RFC3339 or YYYY-MM-DD (defaults to 30 days before to)"
+// @Param to query string false "End of the range (exclusive), RFC3339 or YYYY-MM-DD (defaults to now)"
+// @Param bins query int false "Cells spanning the page width, between 10 and 200 (defaults to 50)"
+// @Param limit query int false "Elements to return, at most 100 (defaults to 10)"
+// @Param filter query string false "Filter expression, e.g. device_type = desktop"
+// @Success 200 {object} types.HeatmapResponse "Click heatmap"
+// @Failure 400 {object} map[string]interface{} "Invalid request or validation failed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/analytics/{project_id}/heatmap [get]
+func (s *AnalyticsService) Heatmap(c *ctx.Ctx) (*types.HeatmapResponse, error) {
+	var req types.HeatmapRequest
+	if err := c.Echo.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request parameters")
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if req.Bins == 0 {
+		req.Bins = types.DefaultHeatmapBins
+	}
+
+	if req.Limit == 0 {
+		req.Limit = types.DefaultHeatmapElements
+	}
+
+	scope, err := s.reportScope(c, &req.ReportRequest, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+
+	heatmap, err := s.data.Heatmap(c, scope, req.PagePath, req.Bins, req.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get heatmap: %w", err)
+	}
+
+	return heatmap, nil
+}
+
+// @Summary Get retention cohorts
+// @Description Get visitors grouped into cohorts by the week or month they were first seen for the project, with how many of them return in every following week or month
+// @Tags Analytics
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param project_id path string true "Project ID"
+// @Param from query string false "Start of the range, RFC3339 or YYYY-MM-DD (defaults to 30 days before to)"
+// @Param to query string false "End of the range (exclusive), RFC3339 or YYYY-MM-DD (defaults to now)"
+// @Param interval query string false "Cohort interval: week or month (defaults to week)"
+// @Param timezone query string false "IANA timezone cohorts are aligned to (defaults to UTC)"
+// @Param event_name query string false "Only count visitors returning with this custom event"
+// @Param filter query string false "Filter expression, e.g. country = DE and page_path starts with /blog"
+// @Success 200 {object} types.RetentionResponse "Retention cohorts"
+// @Failure 400 {object} map[string]interface{} "Invalid request or validation failed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/analytics/{project_id}/retention [get]
+func (s *AnalyticsService) Retention(c *ctx.Ctx) (*types.RetentionResponse, error) {
+	var req types.RetentionRequest
+	if err := c.Echo.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request parameters")
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if req.Interval == "" {
+		req.Interval = types.IntervalWeek
+	}
+
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+
+	location, err := time.LoadLocation(req.Timezone)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "timezone must be a valid IANA timezone")
+	}
+
+	scope, err := s.reportScope(c, &req.ReportRequest, location)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets, err := data.Buckets(scope.From, scope.To, req.Interval, location)
+	if err != nil || len(buckets) > data.MaxRetentionCohorts {
+		return nil, echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("the date range may contain at most %d %s cohorts", data.MaxRetentionCohorts, req.Interval))
+	}
+
+	cohorts, err := s.data.Retention(c, scope, req.Interval, location, req.EventName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retention: %w", err)
+	}
+
+	return &types.RetentionResponse{
+		From:      scope.From,
+		To:        scope.To,
+		Interval:  req.Interval,
+		Timezone:  location.String(),
+		EventName: req.EventName,
+		Cohorts:   cohorts,
+	}, nil
+}
+
+// @Summary Get paths
+// @Description Get the most common sequences of page views and custom events within sessions after a page or custom event, or leading to it when going backward, along with a Sankey graph of them
+// @Tags Analytics
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param project_id path string true "Project ID"
+// @Param node_type query string true "Type of the chosen node: page or event"
+// @Param node query string true "Page path or custom event name of the chosen node"
+// @Param direction query string false "forward to follow what happens after the node, backward for what leads to it (defaults to forward)"
+// @Param depth query int false "Steps to follow from the node, at most 10 (defaults to 5)"
+// @Param limit query int false "Paths to return, at most 100 (defaults to 20)"
+// @Param from query string false "Start of the range, RFC3339 or YYYY-MM-DD (defaults to 30 days before to)"
+// @Param to query string false "End of the range (exclusive), RFC3339 or YYYY-MM-DD (defaults to now)"
+// @Param filter query string false "Filter expression, e.g. country = DE and page_path starts with /blog"
+// @Success 200 {object} types.PathsResponse "Paths"
+// @Failure 400 {object} map[string]interface{} "Invalid request or validation failed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/analytics/{project_id}/paths [get]
+func (s *AnalyticsService) Paths(c *ctx.Ctx) (*types.PathsResponse, error) {
+	var req types.PathsRequest
+	if err := c.Echo.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request parameters")
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if req.Direction == "" {
+		req.Direction = types.PathDirectionForward
+	}
+
+	if req.Depth == 0 {
+		req.Depth = types.DefaultPathDepth
+	}
+
+	if req.Limit == 0 {
+		req.Limit = types.DefaultPathLimit
+	}
+
+	scope, err := s.reportScope(c, &req.ReportRequest, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+
+	node := data.PathNode(req.NodeType, req.Node)
+	paths, err := s.data.Paths(c, scope, node, req.Depth, req.Direction == types.PathDirectionBackward, req.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get paths: %w", err)
+	}
+
+	paths.Direction = req.Direction
+	paths.Start = data.ParsePathNode(node)
+
+	return paths, nil
+}
+
+// @Summary Get goal conversions
+// @Description Get the visitors and events reaching every goal of the project with their conversion rate, optionally broken down by a dimension, channel and source credit conversions to the landing event of the session
+// @Tags Analytics
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param project_id path string true "Project ID"
+// @Param dimension query string false "Dimension to break conversions down by: page_path, host, referrer_domain, country, region, city, timezone, isp, browser, os, device_type, event_name, utm_source, utm_medium, utm_campaign, channel or source"
+// @Param limit query int false "Dimension values to return, at most 100 (defaults to 10)"
+// @Param from query string false "Start of the range, RFC3339 or YYYY-MM-DD (defaults to 30 days before to)"
+// @Param to query string false "End of the range (exclusive), RFC3339 or YYYY-MM-DD (defaults to now)"
+// @Param filter query string false "Filter expression, e.g. country = DE and page_path starts with /blog"
+// @Success 200 {object} types.GoalConversionsResponse "Goal conversions"
+// @Failure 400 {object} map[string]interface{} "Invalid request or validation failed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/analytics/{project_id}/goals [get]
+func (s *AnalyticsService) GoalConversions(c *ctx.Ctx) (*types.GoalConversionsResponse, error) {
+	var req types.GoalConversionsRequest
+	if err := c.Echo.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request parameters")
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if _, ok := data.Dimensions[req.Dimension]; req.Dimension != "" && !ok {
+		return nil, echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("dimension must be one of: %s", strings.Join(data.DimensionNames(), ", ")))
+	}
+
+	if req.Limit == 0 {
+		req.Limit = types.DefaultBreakdownLimit
+	}
+
+	scope, err := s.reportScope(c, &req.ReportRequest, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+
+	goals, err := s.goalService.ListProjectGoals(c, scope.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list goals: %w", err)
+	}
+
+	conversions, err := s.data.GoalConversions(c, scope, goals, req.Dimension, req.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get goal conversions: %w", err)
+	}
+
+	return conversions, nil
+}
+
+// reportScope validates the report request, makes sure the project belongs to the organization of the caller
+// and parses its date range, dates start at midnight in location
+func (s *AnalyticsService) reportScope(c *ctx.Ctx, req *types.ReportRequest, location *time.Location) (*data.Scope, error) {
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	from, to, err := req.Period(time.Now(), location)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	filter, err := filters.Parse(req.Filter, data.Dimensions)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	project, err := s.organizationProject(c, req.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.Scope{
+		OrganizationID: project.OrganizationID,
+		ProjectID:      project.ID,
+		From:           from,
+		To:             to,
+		Filter:         filter,
+		SessionTimeout: project.SessionTimeout(),
+	}, nil
+}
+
+// organizationProject returns the project when it belongs to the organization of the caller, 404 otherwise
+func (s *AnalyticsService) organizationProject(c *ctx.Ctx, projectID string) (*models.Project, error) {
+	project, err := s.projectService.GetOrganizationProject(c, projectID, c.OrgID())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Project not found")
+		}
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+
+	return project, nil
+}
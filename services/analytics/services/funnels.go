@@ -0,0 +1,296 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"zori/internal/ctx"
+	"zori/internal/storage/postgres/models"
+	"zori/internal/utils"
+	"zori/services/analytics/types"
+
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary List funnels
+// @Description Get the funnels defined for a project
+// @Tags Funnels
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param project_id path string true "Project ID"
+// @Success 200 {object} types.ListFunnelsResponse "List of funnels"
+// @Failure 400 {object} map[string]interface{} "Invalid request or validation failed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/analytics/{project_id}/funnels [get]
+func (s *AnalyticsService) ListFunnels(c *ctx.Ctx) (*types.ListFunnelsResponse, error) {
+	project, err := s.funnelProject(c)
+	if err != nil {
+		return nil, err
+	}
+
+	funnels, err := s.funnelData.ListProjectFunnels(c, project.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list funnels: %w", err)
+	}
+
+	return &types.ListFunnelsResponse{
+		Funnels: funnels,
+		Total:   len(funnels),
+	}, nil
+}
+
+// @Summary Create a funnel
+// @Description Define an ordered list of steps for a project, steps match page views by path, custom events by name or clicks by the clicked element
+// @Tags Funnels
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param project_id path string true "Project ID"
+// @Param request body types.FunnelRequest true "Funnel definition"
+// @Success 201 {object} models.Funnel "Created funnel"
+// @Failure 400 {object} map[string]interface{} "Invalid request or validation failed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/analytics/{project_id}/funnels [post]
+func (s *AnalyticsService) CreateFunnel(c *ctx.Ctx) (*models.Funnel, error) {
+	req, err := bindFunnelRequest(c)
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := s.organizationProject(c, req.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	funnel, err := s.funnelData.CreateFunnel(c, project.ID, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create funnel: %w", err)
+	}
+
+	c.Echo.Response().Status = http.StatusCreated
+
+	return funnel, nil
+}
+
+// @Summary Get a funnel
+// @Description Get a single funnel definition by its ID
+// @Tags Funnels
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param project_id path string true "Project ID"
+// @Param funnel_id path string true "Funnel ID"
+// @Success 200 {object} models.Funnel "Funnel details"
+// @Failure 400 {object} map[string]interface{} "Invalid request or validation failed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project or funnel not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/analytics/{project_id}/funnels/{funnel_id} [get]
+func (s *AnalyticsService) GetFunnel(c *ctx.Ctx) (*models.Funnel, error) {
+	project, funnelID, err := s.funnelPath(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.projectFunnel(c, project.ID, funnelID)
+}
+
+// @Summary Update a funnel
+// @Description Replace the name, steps and conversion window of a funnel
+// @Tags Funnels
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param project_id path string true "Project ID"
+// @Param funnel_id path string true "Funnel ID"
+// @Param request body types.FunnelRequest true "Funnel definition"
+// @Success 200 {object} models.Funnel "Updated funnel"
+// @Failure 400 {object} map[string]interface{} "Invalid request or validation failed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project or funnel not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/analytics/{project_id}/funnels/{funnel_id} [put]
+func (s *AnalyticsService) UpdateFunnel(c *ctx.Ctx) (*models.Funnel, error) {
+	req, err := bindFunnelRequest(c)
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := s.organizationProject(c, req.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := s.funnelData.FunnelExists(c, project.ID, req.FunnelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check funnel existence: %w", err)
+	}
+	if !exists {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Funnel not found")
+	}
+
+	funnel, err := s.funnelData.UpdateFunnel(c, project.ID, req.FunnelID, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update funnel: %w", err)
+	}
+
+	return funnel, nil
+}
+
+// @Summary Delete a funnel
+// @Description Delete a funnel definition, events are not affected
+// @Tags Funnels
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param project_id path string true "Project ID"
+// @Param funnel_id path string true "Funnel ID"
+// @Success 200 {object} map[string]string "Deletion confirmation"
+// @Failure 400 {object} map[string]interface{} "Invalid request or validation failed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project or funnel not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/analytics/{project_id}/funnels/{funnel_id} [delete]
+func (s *AnalyticsService) DeleteFunnel(c *ctx.Ctx) (map[string]string, error) {
+	project, funnelID, err := s.funnelPath(c)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := s.funnelData.FunnelExists(c, project.ID, funnelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check funnel existence: %w", err)
+	}
+	if !exists {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Funnel not found")
+	}
+
+	if err := s.funnelData.DeleteFunnel(c, project.ID, funnelID); err != nil {
+		return nil, fmt.Errorf("failed to delete funnel: %w", err)
+	}
+
+	return map[string]string{
+		"message": "Funnel deleted successfully",
+	}, nil
+}
+
+// @Summary Get funnel conversion
+// @Description Get the visitors completing every step of the funnel in order within its conversion window, with the conversion and drop-off of every step
+// @Tags Funnels
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param project_id path string true "Project ID"
+// @Param funnel_id path string true "Funnel ID"
+// @Param from query string false "Start of the range, RFC3339 or YYYY-MM-DD (defaults to 30 days before to)"
+// @Param to query string false "End of the range (exclusive), RFC3339 or YYYY-MM-DD (defaults to now)"
+// @Param filter query string false "Filter expression, e.g. country = DE and page_path starts with /blog"
+// @Success 200 {object} types.FunnelReportResponse "Funnel conversion"
+// @Failure 400 {object} map[string]interface{} "Invalid request or validation failed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project or funnel not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/analytics/{project_id}/funnels/{funnel_id}/report [get]
+func (s *AnalyticsService) FunnelReport(c *ctx.Ctx) (*types.FunnelReportResponse, error) {
+	var req types.FunnelReportRequest
+	if err := c.Echo.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request parameters")
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	scope, err := s.reportScope(c, &req.ReportRequest, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+
+	funnel, err := s.projectFunnel(c, scope.ProjectID, req.FunnelID)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := s.data.Funnel(c, scope, funnel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get funnel report: %w", err)
+	}
+
+	return report, nil
+}
+
+// bindFunnelRequest binds and validates a funnel definition, the conversion window defaults to one day
+func bindFunnelRequest(c *ctx.Ctx) (*types.FunnelRequest, error) {
+	var req types.FunnelRequest
+	if err := c.Echo.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if req.WindowMinutes == 0 {
+		req.WindowMinutes = models.DefaultFunnelWindowMinutes
+	}
+
+	for idx := range req.Steps {
+		if req.Steps[idx].Match == "" {
+			req.Steps[idx].Match = models.FunnelMatchEquals
+		}
+	}
+
+	return &req, nil
+}
+
+// funnelProject validates the path of a funnel request and returns the project of the funnel
+func (s *AnalyticsService) funnelProject(c *ctx.Ctx) (*models.Project, error) {
+	var req types.FunnelPathRequest
+	if err := c.Echo.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request parameters")
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return s.organizationProject(c, req.ProjectID)
+}
+
+// funnelPath validates the path of a request to a single funnel and returns the project and ID of the funnel
+func (s *AnalyticsService) funnelPath(c *ctx.Ctx) (*models.Project, string, error) {
+	var req types.FunnelIDRequest
+	if err := c.Echo.Bind(&req); err != nil {
+		return nil, "", echo.NewHTTPError(http.StatusBadRequest, "Invalid request parameters")
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, "", echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	project, err := s.organizationProject(c, req.ProjectID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return project, req.FunnelID, nil
+}
+
+func (s *AnalyticsService) projectFunnel(c *ctx.Ctx, projectID string, funnelID string) (*models.Funnel, error) {
+	funnel, err := s.funnelData.GetFunnel(c, projectID, funnelID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Funnel not found")
+		}
+		return nil, fmt.Errorf("failed to get funnel: %w", err)
+	}
+
+	return funnel, nil
+}
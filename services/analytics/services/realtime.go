@@ -0,0 +1,164 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	"zori/internal/ctx"
+	"zori/internal/natsstream"
+	"zori/internal/utils"
+	"zori/services/analytics/data"
+	"zori/services/analytics/types"
+	ingestionTypes "zori/services/ingestion/types"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// liveEventsBuffer is how many events may wait for a slow client, further events are dropped by NATS
+	liveEventsBuffer = 256
+	// liveEventsHeartbeat keeps idle connections from being closed by proxies
+	liveEventsHeartbeat = 15 * time.Second
+)
+
+// @Summary Get realtime visitors
+// @Description Get the visitors active in the last 5 minutes and the pages they are on
+// @Tags Analytics
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param project_id path string true "Project ID"
+// @Param limit query int false "Pages to return, at most 100 (defaults to 10)"
+// @Success 200 {object} types.RealtimeResponse "Active visitors"
+// @Failure 400 {object} map[string]interface{} "Invalid request or validation failed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/analytics/{project_id}/realtime [get]
+func (s *AnalyticsService) Realtime(c *ctx.Ctx) (*types.RealtimeResponse, error) {
+	var req types.RealtimeRequest
+	if err := c.Echo.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request parameters")
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if req.Limit == 0 {
+		req.Limit = types.DefaultBreakdownLimit
+	}
+
+	project, err := s.organizationProject(c, req.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the window is measured by server time, client clocks can be off by any amount. Events up to a minute in the
+	// future still count as active in case the clock of the processor runs slightly ahead of this one.
+	now := time.Now().UTC()
+	scope := &data.Scope{
+		OrganizationID: project.OrganizationID,
+		ProjectID:      project.ID,
+		From:           now.Add(-types.ActiveVisitorsWindow),
+		To:             now.Add(time.Minute),
+		ServerTime:     true,
+	}
+
+	realtime, err := s.data.Realtime(c, scope, req.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get realtime visitors: %w", err)
+	}
+
+	return realtime, nil
+}
+
+// @Summary Stream live events
+// @Description Stream the events of the organization as they are stored, as server-sent events named "event" whose data is the enriched event. The stream covers every project of the organization unless project_id is set. Events published while the client is too slow to read them are dropped.
+// @Tags Analytics
+// @Produce text/event-stream
+// @Security ApiKeyAuth
+// @Param project_id query string false "Only stream the events of this project"
+// @Success 200 {string} string "Stream of server-sent events"
+// @Failure 400 {object} map[string]interface{} "Invalid request or validation failed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/analytics/live [get]
+func (s *AnalyticsService) LiveEvents(c *ctx.Ctx) error {
+	var req types.LiveEventsRequest
+	if err := c.Echo.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request parameters")
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	projectID := "*"
+	if req.ProjectID != "" {
+		project, err := s.organizationProject(c, req.ProjectID)
+		if err != nil {
+			return err
+		}
+		projectID = project.ID
+	}
+
+	messages := make(chan *nats.Msg, liveEventsBuffer)
+	subject := natsstream.EnrichedEventsProjectSubject(c.OrgID(), projectID)
+	subscription, err := s.natsStream.GetConnection().ChanSubscribe(subject, messages)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to live events: %w", err)
+	}
+	defer subscription.Unsubscribe()
+
+	response := c.Echo.Response()
+	response.Header().Set(echo.HeaderContentType, "text/event-stream")
+	response.Header().Set(echo.HeaderCacheControl, "no-cache")
+	response.Header().Set(echo.HeaderConnection, "keep-alive")
+	response.Header().Set("X-Accel-Buffering", "no")
+	response.WriteHeader(http.StatusOK)
+	response.Flush()
+
+	heartbeat := time.NewTicker(liveEventsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var writeErr error
+		select {
+		case <-c.Done():
+			return nil
+		case <-heartbeat.C:
+			_, writeErr = fmt.Fprint(response, ": heartbeat\n\n")
+		case msg := <-messages:
+			event, err := liveEvent(msg.Data)
+			if err != nil {
+				log.Printf("Error decoding live event: %v", err)
+				continue
+			}
+			_, writeErr = fmt.Fprintf(response, "event: event\ndata: %s\n\n", event)
+		}
+
+		if writeErr != nil {
+			return nil
+		}
+		response.Flush()
+	}
+}
+
+// liveEvent returns the enriched event without the IP address of the visitor
+func liveEvent(frameBytes []byte) ([]byte, error) {
+	var eventFrame ingestionTypes.ClientEventFrameV1
+	if err := json.Unmarshal(frameBytes, &eventFrame); err != nil {
+		return nil, err
+	}
+
+	if eventFrame.ClientEventV1 != nil {
+		eventFrame.IP = ""
+	}
+
+	return json.Marshal(&eventFrame)
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"zori/internal/ctx"
+	"zori/internal/utils"
+	"zori/services/analytics/data"
+	"zori/services/analytics/types"
+
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Get visitor profile
+// @Description Get everything a visitor did over their whole history: first and last seen, sessions, devices, locations and their chronological event timeline
+// @Tags Analytics
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param project_id path string true "Project ID"
+// @Param visitor_id path string true "Visitor ID"
+// @Param limit query int false "Timeline events per page, at most 500 (defaults to 50)"
+// @Param page query int false "Timeline page number starting at 1"
+// @Success 200 {object} types.VisitorResponse "Visitor profile"
+// @Failure 400 {object} map[string]interface{} "Invalid request or validation failed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project or visitor not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/analytics/{project_id}/visitors/{visitor_id} [get]
+func (s *AnalyticsService) Visitor(c *ctx.Ctx) (*types.VisitorResponse, error) {
+	var req types.VisitorRequest
+	if err := c.Echo.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request parameters")
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	scope, err := s.timelineScope(c, &req.TimelineRequest)
+	if err != nil {
+		return nil, err
+	}
+	scope.Filter = data.VisitorFilter(req.VisitorID)
+
+	visitor, err := s.timeline(c, scope, &req.TimelineRequest, "Visitor not found")
+	if err != nil {
+		return nil, err
+	}
+	visitor.VisitorID = req.VisitorID
+
+	visitor.UserID, err = s.data.VisitorUserID(c, scope, req.VisitorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get visitor user: %w", err)
+	}
+
+	if visitor.UserID != "" {
+		visitor.Traits, err = s.data.UserTraits(c, scope, visitor.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user traits: %w", err)
+		}
+	}
+
+	return visitor, nil
+}
+
+// @Summary Get user profile
+// @Description Get everything the visitors linked to a user by identify and alias events did: first and last seen, sessions, devices, locations and their chronological event timeline
+// @Tags Analytics
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param project_id path string true "Project ID"
+// @Param user_id path string true "User ID sent with identify events"
+// @Param limit query int false "Timeline events per page, at most 500 (defaults to 50)"
+// @Param page query int false "Timeline page number starting at 1"
+// @Success 200 {object} types.VisitorResponse "User profile"
+// @Failure 400 {object} map[string]interface{} "Invalid request or validation failed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project or user not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/analytics/{project_id}/users/{user_id} [get]
+func (s *AnalyticsService) User(c *ctx.Ctx) (*types.VisitorResponse, error) {
+	var req types.UserRequest
+	if err := c.Echo.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request parameters")
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	scope, err := s.timelineScope(c, &req.TimelineRequest)
+	if err != nil {
+		return nil, err
+	}
+	scope.Filter = data.UserFilter(scope, req.UserID)
+
+	user, err := s.timeline(c, scope, &req.TimelineRequest, "User not found")
+	if err != nil {
+		return nil, err
+	}
+	user.UserID = req.UserID
+
+	user.VisitorIDs, err = s.data.UserVisitorIDs(c, scope, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user visitors: %w", err)
+	}
+
+	user.Traits, err = s.data.UserTraits(c, scope, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user traits: %w", err)
+	}
+
+	return user, nil
+}
+
+// timelineScope returns the scope of a profile, it covers the whole history of the project and its end leaves
+// room for clients running slightly ahead
+func (s *AnalyticsService) timelineScope(c *ctx.Ctx, req *types.TimelineRequest) (*data.Scope, error) {
+	if req.Limit == 0 {
+		req.Limit = types.DefaultVisitorEventsLimit
+	}
+
+	if req.Page == 0 {
+		req.Page = 1
+	}
+
+	project, err := s.organizationProject(c, req.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.Scope{
+		OrganizationID: project.OrganizationID,
+		ProjectID:      project.ID,
+		From:           time.Unix(0, 0).UTC(),
+		To:             time.Now().UTC().Add(time.Minute),
+		SessionTimeout: project.SessionTimeout(),
+	}, nil
+}
+
+// timeline returns the profile of the events in scope, notFound is the message of the 404 returned without events
+func (s *AnalyticsService) timeline(c *ctx.Ctx, scope *data.Scope, req *types.TimelineRequest, notFound string) (*types.VisitorResponse, error) {
+	profile, err := s.data.Visitor(c, scope, req.Limit, (req.Page-1)*req.Limit)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, notFound)
+		}
+		return nil, fmt.Errorf("failed to get timeline: %w", err)
+	}
+
+	profile.Page = req.Page
+	profile.Limit = req.Limit
+
+	return profile, nil
+}
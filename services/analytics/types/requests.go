@@ -0,0 +1,221 @@
+package types
+
+import (
+	"errors"
+	"time"
+	"zori/internal/storage/postgres/models"
+)
+
+// DefaultReportPeriod is used when a report request does not specify where its date range starts
+const DefaultReportPeriod = 30 * 24 * time.Hour
+
+// ReportRequest holds the parameters shared by every analytics report.
+// From and To accept either RFC3339 timestamps or YYYY-MM-DD dates, To is exclusive. Dates start at midnight
+// in the timezone of the report, or in UTC for reports without one.
+type ReportRequest struct {
+	ProjectID string `param:"project_id" json:"-" validate:"required,uuid"`
+	From      string `query:"from" json:"from" example:"2024-01-01"`
+	To        string `query:"to" json:"to" example:"2024-02-01"`
+	// Filter is a filter expression restricting the events of the report, see the filters package
+	Filter string `query:"filter" json:"filter" example:"country = DE and page_path starts with /blog"`
+}
+
+type OverviewRequest struct {
+	ReportRequest
+}
+
+// Breakdown pagination limits
+const (
+	DefaultBreakdownLimit = 10
+	MaxBreakdownLimit     = 100
+)
+
+type BreakdownRequest struct {
+	ReportRequest
+	Dimension string `query:"dimension" json:"dimension" validate:"required" example:"page_path"`
+	Limit     int    `query:"limit" json:"limit" validate:"omitempty,min=1,max=100" example:"10"`
+	Page      int    `query:"page" json:"page" validate:"omitempty,min=1" example:"1"`
+}
+
+// DefaultSessionPagesLimit is the number of entry and exit pages returned by default
+const DefaultSessionPagesLimit = 10
+
+type SessionsRequest struct {
+	ReportRequest
+	// Limit caps the number of entry and exit pages
+	Limit int `query:"limit" json:"limit" validate:"omitempty,min=1,max=100" example:"10"`
+}
+
+type RetentionRequest struct {
+	ReportRequest
+	Interval string `query:"interval" json:"interval" validate:"omitempty,oneof=week month" example:"week"`
+	// Timezone is an IANA timezone name cohorts are aligned to, defaults to UTC
+	Timezone string `query:"timezone" json:"timezone" example:"Europe/Berlin"`
+	// EventName restricts returning visits to a custom event
+	EventName string `query:"event_name" json:"event_name" example:"purchase"`
+}
+
+type GoalConversionsRequest struct {
+	ReportRequest
+	// Dimension breaks conversions down by the top values of a dimension
+	Dimension string `query:"dimension" json:"dimension" example:"country"`
+	Limit     int    `query:"limit" json:"limit" validate:"omitempty,min=1,max=100" example:"10"`
+}
+
+// Visitor timeline pagination limits
+const (
+	DefaultVisitorEventsLimit = 50
+	MaxVisitorEventsLimit     = 500
+)
+
+// TimelineRequest holds the parameters shared by visitor and user profiles, Limit and Page paginate the timeline
+type TimelineRequest struct {
+	ProjectID string `param:"project_id" json:"-" validate:"required,uuid"`
+	Limit     int    `query:"limit" json:"limit" validate:"omitempty,min=1,max=500" example:"50"`
+	Page      int    `query:"page" json:"page" validate:"omitempty,min=1" example:"1"`
+}
+
+type VisitorRequest struct {
+	TimelineRequest
+	VisitorID string `param:"visitor_id" json:"-" validate:"required,max=255"`
+}
+
+// UserRequest selects the profile of every visitor linked to the user by identify and alias events
+type UserRequest struct {
+	TimelineRequest
+	UserID string `param:"user_id" json:"-" validate:"required,max=255"`
+}
+
+// ActiveVisitorsWindow is how long a visitor counts as active after their last event
+const ActiveVisitorsWindow = 5 * time.Minute
+
+type RealtimeRequest struct {
+	ProjectID string `param:"project_id" json:"-" validate:"required,uuid"`
+	Limit     int    `query:"limit" json:"limit" validate:"omitempty,min=1,max=100" example:"10"`
+}
+
+// LiveEventsRequest streams the events of a single project, or of every project of the organization without ProjectID
+type LiveEventsRequest struct {
+	ProjectID string `query:"project_id" json:"project_id" validate:"omitempty,uuid" example:"550e8400-e29b-41d4-a716-446655440000"`
+}
+
+// Path directions
+const (
+	PathDirectionForward  = "forward"
+	PathDirectionBackward = "backward"
+)
+
+// Paths defaults
+const (
+	DefaultPathDepth = 5
+	DefaultPathLimit = 20
+)
+
+// PathsRequest explores the paths starting from a page or custom event, or ending at it when going backward
+type PathsRequest struct {
+	ReportRequest
+	NodeType  string `query:"node_type" json:"node_type" validate:"required,oneof=page event" example:"page"`
+	Node      string `query:"node" json:"node" validate:"required" example:"/pricing"`
+	Direction string `query:"direction" json:"direction" validate:"omitempty,oneof=forward backward" example:"forward"`
+	Depth     int    `query:"depth" json:"depth" validate:"omitempty,min=1,max=10" example:"5"`
+	Limit     int    `query:"limit" json:"limit" validate:"omitempty,min=1,max=100" example:"20"`
+}
+
+// Heatmap defaults
+const (
+	DefaultHeatmapBins     = 50
+	DefaultHeatmapElements = 10
+)
+
+type HeatmapRequest struct {
+	ReportRequest
+	PagePath string `query:"page_path" json:"page_path" validate:"required" example:"/pricing"`
+	// Bins is the number of cells spanning the page width
+	Bins  int `query:"bins" json:"bins" validate:"omitempty,min=10,max=200" example:"50"`
+	Limit int `query:"limit" json:"limit" validate:"omitempty,min=1,max=100" example:"10"`
+}
+
+// FunnelPathRequest identifies a funnel, or only its project when creating or listing funnels
+type FunnelPathRequest struct {
+	ProjectID string `param:"project_id" json:"-" validate:"required,uuid"`
+	FunnelID  string `param:"funnel_id" json:"-" validate:"omitempty,uuid"`
+}
+
+// FunnelIDRequest identifies a single funnel of a project
+type FunnelIDRequest struct {
+	ProjectID string `param:"project_id" json:"-" validate:"required,uuid"`
+	FunnelID  string `param:"funnel_id" json:"-" validate:"required,uuid"`
+}
+
+// FunnelRequest creates or replaces a funnel, steps have to be completed in order
+type FunnelRequest struct {
+	FunnelPathRequest
+	Name  string              `json:"name" validate:"required,max=255" example:"Signup"`
+	Steps []models.FunnelStep `json:"steps" validate:"required,min=2,max=10,dive"`
+	// WindowMinutes defaults to one day, at most 30 days
+	WindowMinutes int `json:"window_minutes" validate:"omitempty,min=1,max=43200" example:"1440"`
+}
+
+type FunnelReportRequest struct {
+	ReportRequest
+	FunnelID string `param:"funnel_id" json:"funnel_id" validate:"required,uuid" example:"770e8400-e29b-41d4-a716-446655440002"`
+}
+
+// Period parses the requested date range, defaulting to the last 30 days. Dates start at midnight in location,
+// the timezone the report is shown in.
+func (r *ReportRequest) Period(now time.Time, location *time.Location) (time.Time, time.Time, error) {
+	to := now.UTC()
+	if r.To != "" {
+		parsedTo, err := parseReportTime(r.To, location)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("to must be a RFC3339 timestamp or a YYYY-MM-DD date")
+		}
+		to = parsedTo
+	}
+
+	from := to.Add(-DefaultReportPeriod)
+	if r.From != "" {
+		parsedFrom, err := parseReportTime(r.From, location)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("from must be a RFC3339 timestamp or a YYYY-MM-DD date")
+		}
+		from = parsedFrom
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, errors.New("from must be before to")
+	}
+
+	return from, to, nil
+}
+
+func parseReportTime(value string, location *time.Location) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed.UTC(), nil
+	}
+
+	parsed, err := time.ParseInLocation(time.DateOnly, value, location)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return parsed.UTC(), nil
+}
+
+// Time series intervals
+const (
+	IntervalMinute = "minute"
+	IntervalHour   = "hour"
+	IntervalDay    = "day"
+	IntervalWeek   = "week"
+	IntervalMonth  = "month"
+)
+
+type TimeseriesRequest struct {
+	ReportRequest
+	Interval string `query:"interval" json:"interval" validate:"omitempty,oneof=minute hour day week month" example:"day"`
+	// Timezone is an IANA timezone name buckets are aligned to, defaults to UTC
+	Timezone string `query:"timezone" json:"timezone" example:"Europe/Berlin"`
+	// Compare adds the same series for the period right before the requested one
+	Compare bool `query:"compare" json:"compare" example:"true"`
+}
@@ -0,0 +1,63 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReportRequestPeriod(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		request      ReportRequest
+		expectedFrom time.Time
+		expectedTo   time.Time
+		expectErr    bool
+	}{
+		{"defaults to last 30 days", ReportRequest{}, now.Add(-DefaultReportPeriod), now, false},
+		{"dates", ReportRequest{From: "2024-01-01", To: "2024-02-01"}, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), false},
+		{"timestamps", ReportRequest{From: "2024-01-01T10:00:00+02:00", To: "2024-01-01T12:00:00Z"}, time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), false},
+		{"invalid from", ReportRequest{From: "yesterday"}, time.Time{}, time.Time{}, true},
+		{"from after to", ReportRequest{From: "2024-02-01", To: "2024-01-01"}, time.Time{}, time.Time{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			from, to, err := test.request.Period(now, time.UTC)
+			if test.expectErr {
+				if err == nil {
+					t.Fatal("Expected error, got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !from.Equal(test.expectedFrom) || !to.Equal(test.expectedTo) {
+				t.Errorf("Expected period %s - %s, got %s - %s", test.expectedFrom, test.expectedTo, from, to)
+			}
+		})
+	}
+}
+
+func TestReportRequestPeriodDatesInTimezone(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("Failed to load timezone: %v", err)
+	}
+
+	request := ReportRequest{From: "2024-01-01", To: "2024-07-01T00:00:00Z"}
+	from, to, err := request.Period(time.Now(), location)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if expected := time.Date(2023, 12, 31, 23, 0, 0, 0, time.UTC); !from.Equal(expected) {
+		t.Errorf("Expected dates to start at midnight in the timezone %s, got %s", expected, from)
+	}
+	if expected := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC); !to.Equal(expected) {
+		t.Errorf("Expected timestamps to keep their offset %s, got %s", expected, to)
+	}
+}
@@ -0,0 +1,310 @@
+package types
+
+import (
+	"time"
+	"zori/internal/storage/postgres/models"
+)
+
+// OverviewResponse represents aggregated traffic of a project over a date range
+type OverviewResponse struct {
+	From     time.Time `json:"from" example:"2024-01-01T00:00:00Z"`
+	To       time.Time `json:"to" example:"2024-02-01T00:00:00Z"`
+	Visitors uint64    `json:"visitors" example:"1250"`
+	// People counts visitors linked to the same user by identify and alias events once
+	People       uint64 `json:"people" example:"1100"`
+	PageViews    uint64 `json:"page_views" example:"4830"`
+	Clicks       uint64 `json:"clicks" example:"920"`
+	CustomEvents uint64 `json:"custom_events" example:"310"`
+	Events       uint64 `json:"events" example:"6060"`
+}
+
+// TimeseriesPoint holds the traffic of a single bucket, Time is the start of the bucket in the requested timezone
+type TimeseriesPoint struct {
+	Time      time.Time `json:"time" example:"2024-01-01T00:00:00+01:00"`
+	Visitors  uint64    `json:"visitors" example:"120"`
+	PageViews uint64    `json:"page_views" example:"430"`
+	Events    uint64    `json:"events" example:"610"`
+}
+
+// TimeseriesResponse represents traffic of a project grouped by interval, empty buckets are filled with zeros.
+// Previous is only present when comparing, it has the length of Points and every point is the bucket at the same
+// offset from the start of the previous period, zero filled where the previous period has no such bucket.
+type TimeseriesResponse struct {
+	From     time.Time         `json:"from" example:"2024-01-01T00:00:00Z"`
+	To       time.Time         `json:"to" example:"2024-02-01T00:00:00Z"`
+	Interval string            `json:"interval" example:"day"`
+	Timezone string            `json:"timezone" example:"Europe/Berlin"`
+	Points   []TimeseriesPoint `json:"points"`
+	Previous []TimeseriesPoint `json:"previous,omitempty"`
+}
+
+// BreakdownRow holds the traffic of a single dimension value
+type BreakdownRow struct {
+	Value    string  `json:"value" example:"/pricing"`
+	Visitors uint64  `json:"visitors" example:"320"`
+	Views    uint64  `json:"views" example:"780"`
+	Events   uint64  `json:"events" example:"1020"`
+	Share    float64 `json:"share" example:"0.256"`
+}
+
+// BreakdownResponse represents a page of the top values of a dimension, Total is the number of distinct values
+type BreakdownResponse struct {
+	Dimension string         `json:"dimension" example:"page_path"`
+	From      time.Time      `json:"from" example:"2024-01-01T00:00:00Z"`
+	To        time.Time      `json:"to" example:"2024-02-01T00:00:00Z"`
+	Page      int            `json:"page" example:"1"`
+	Limit     int            `json:"limit" example:"10"`
+	Total     uint64         `json:"total" example:"42"`
+	Rows      []BreakdownRow `json:"rows"`
+}
+
+// SessionPage holds the sessions entering or leaving through a page
+type SessionPage struct {
+	Page       string  `json:"page" example:"/blog/launch"`
+	Sessions   uint64  `json:"sessions" example:"210"`
+	BounceRate float64 `json:"bounce_rate" example:"0.42"`
+}
+
+// SessionsResponse represents the sessions of a project over a date range, a session bounces when it has exactly one page view
+type SessionsResponse struct {
+	From                  time.Time     `json:"from" example:"2024-01-01T00:00:00Z"`
+	To                    time.Time     `json:"to" example:"2024-02-01T00:00:00Z"`
+	SessionTimeoutMinutes int           `json:"session_timeout_minutes" example:"30"`
+	Sessions              uint64        `json:"sessions" example:"1730"`
+	BounceRate            float64       `json:"bounce_rate" example:"0.38"`
+	AvgDurationSeconds    float64       `json:"avg_duration_seconds" example:"143.5"`
+	AvgPageViews          float64       `json:"avg_page_views" example:"2.7"`
+	EntryPages            []SessionPage `json:"entry_pages"`
+	ExitPages             []SessionPage `json:"exit_pages"`
+}
+
+// FunnelStepResult holds the visitors who completed a step and all the steps before it. ConversionRate is relative
+// to the first step, StepConversionRate and DropOff are relative to the previous step.
+type FunnelStepResult struct {
+	Step               int     `json:"step" example:"2"`
+	Type               string  `json:"type" example:"page_view"`
+	Match              string  `json:"match" example:"equals"`
+	Value              string  `json:"value" example:"/signup"`
+	Visitors           uint64  `json:"visitors" example:"180"`
+	ConversionRate     float64 `json:"conversion_rate" example:"0.36"`
+	StepConversionRate float64 `json:"step_conversion_rate" example:"0.45"`
+	DropOff            uint64  `json:"drop_off" example:"220"`
+}
+
+// FunnelReportResponse represents the conversion of a funnel over a date range
+type FunnelReportResponse struct {
+	FunnelID      string             `json:"funnel_id" example:"770e8400-e29b-41d4-a716-446655440002"`
+	Name          string             `json:"name" example:"Signup"`
+	From          time.Time          `json:"from" example:"2024-01-01T00:00:00Z"`
+	To            time.Time          `json:"to" example:"2024-02-01T00:00:00Z"`
+	WindowMinutes int                `json:"window_minutes" example:"1440"`
+	Steps         []FunnelStepResult `json:"steps"`
+}
+
+// ListFunnelsResponse represents the funnels of a project
+type ListFunnelsResponse struct {
+	Funnels []*models.Funnel `json:"funnels"`
+	Total   int              `json:"total" example:"3"`
+}
+
+// HeatmapCell holds the clicks within a cell, X and Y count cells from the top left corner of the page.
+// Density is relative to the cell with the most clicks.
+type HeatmapCell struct {
+	X       uint32  `json:"x" example:"12"`
+	Y       uint32  `json:"y" example:"30"`
+	Clicks  uint64  `json:"clicks" example:"84"`
+	Density float64 `json:"density" example:"0.65"`
+}
+
+// HeatmapElement holds the clicks on a page element, Share is relative to all clicks on the page
+type HeatmapElement struct {
+	Element  string  `json:"element" example:"button#signup"`
+	Clicks   uint64  `json:"clicks" example:"320"`
+	Visitors uint64  `json:"visitors" example:"210"`
+	Share    float64 `json:"share" example:"0.18"`
+}
+
+// HeatmapResponse represents the clicks on a page. Cells are square and Bins of them span the page width,
+// so a cell is drawn with a side of width / Bins when the page is rendered at width.
+type HeatmapResponse struct {
+	PagePath string           `json:"page_path" example:"/pricing"`
+	From     time.Time        `json:"from" example:"2024-01-01T00:00:00Z"`
+	To       time.Time        `json:"to" example:"2024-02-01T00:00:00Z"`
+	Bins     int              `json:"bins" example:"50"`
+	Clicks   uint64           `json:"clicks" example:"1780"`
+	Cells    []HeatmapCell    `json:"cells"`
+	Elements []HeatmapElement `json:"elements"`
+}
+
+// RetentionCohort holds the visitors first seen in the interval starting at Cohort, the first cohort only holds
+// visitors first seen within the range when it starts before the range. Retained has an entry for every interval
+// from the cohort one to the end of the range, Retained[0] is the whole cohort and Rates are relative to it.
+type RetentionCohort struct {
+	Cohort   time.Time `json:"cohort" example:"2024-01-01T00:00:00Z"`
+	Visitors uint64    `json:"visitors" example:"420"`
+	Retained []uint64  `json:"retained" example:"420,130,95"`
+	Rates    []float64 `json:"rates" example:"1,0.31,0.23"`
+}
+
+// RetentionResponse represents a retention cohort matrix, EventName is set when returning visits are restricted to a custom event
+type RetentionResponse struct {
+	From      time.Time         `json:"from" example:"2024-01-01T00:00:00Z"`
+	To        time.Time         `json:"to" example:"2024-03-01T00:00:00Z"`
+	Interval  string            `json:"interval" example:"week"`
+	Timezone  string            `json:"timezone" example:"Europe/Berlin"`
+	EventName string            `json:"event_name,omitempty" example:"purchase"`
+	Cohorts   []RetentionCohort `json:"cohorts"`
+}
+
+// Path node types
+const (
+	PathNodePage  = "page"
+	PathNodeEvent = "event"
+)
+
+// PathStep is a page view or a custom event within a path
+type PathStep struct {
+	Type  string `json:"type" example:"page"`
+	Value string `json:"value" example:"/pricing"`
+}
+
+// PathSequence holds the sessions which went through the steps in order
+type PathSequence struct {
+	Steps    []PathStep `json:"steps"`
+	Sessions uint64     `json:"sessions" example:"85"`
+}
+
+// PathNode is a step at a distance from the chosen node, Depth is negative for steps before it
+type PathNode struct {
+	ID       string `json:"id" example:"1:page:/pricing"`
+	Type     string `json:"type" example:"page"`
+	Value    string `json:"value" example:"/pricing"`
+	Depth    int    `json:"depth" example:"1"`
+	Sessions uint64 `json:"sessions" example:"140"`
+}
+
+// PathEdge links two nodes with the sessions moving from Source to Target
+type PathEdge struct {
+	Source   string `json:"source" example:"0:page:/"`
+	Target   string `json:"target" example:"1:page:/pricing"`
+	Sessions uint64 `json:"sessions" example:"140"`
+}
+
+// PathsResponse represents the top paths through a node along with the Sankey graph made of them.
+// Sessions counts every session reaching the node, including those outside of the top paths.
+type PathsResponse struct {
+	From      time.Time      `json:"from" example:"2024-01-01T00:00:00Z"`
+	To        time.Time      `json:"to" example:"2024-02-01T00:00:00Z"`
+	Direction string         `json:"direction" example:"forward"`
+	Start     PathStep       `json:"start"`
+	Depth     int            `json:"depth" example:"5"`
+	Sessions  uint64         `json:"sessions" example:"1200"`
+	Paths     []PathSequence `json:"paths"`
+	Nodes     []PathNode     `json:"nodes"`
+	Edges     []PathEdge     `json:"edges"`
+}
+
+// GoalConversion holds the visitors who reached a goal and the events reaching it, ConversionRate is the share of visitors who reached it
+type GoalConversion struct {
+	GoalID         string  `json:"goal_id" example:"880e8400-e29b-41d4-a716-446655440003"`
+	Name           string  `json:"name" example:"Visited pricing"`
+	Visitors       uint64  `json:"visitors" example:"240"`
+	Conversions    uint64  `json:"conversions" example:"310"`
+	ConversionRate float64 `json:"conversion_rate" example:"0.19"`
+}
+
+// GoalConversionsRow holds the goal conversions of the visitors with a dimension value
+type GoalConversionsRow struct {
+	Value    string           `json:"value" example:"DE"`
+	Visitors uint64           `json:"visitors" example:"320"`
+	Goals    []GoalConversion `json:"goals"`
+}
+
+// GoalConversionsResponse represents the conversions of every goal of a project, Rows are only present with a dimension
+type GoalConversionsResponse struct {
+	From      time.Time            `json:"from" example:"2024-01-01T00:00:00Z"`
+	To        time.Time            `json:"to" example:"2024-02-01T00:00:00Z"`
+	Dimension string               `json:"dimension,omitempty" example:"country"`
+	Visitors  uint64               `json:"visitors" example:"1250"`
+	Goals     []GoalConversion     `json:"goals"`
+	Rows      []GoalConversionsRow `json:"rows,omitempty"`
+}
+
+// VisitorEvent is a single event of a visitor timeline, Type is page_view, click or event
+type VisitorEvent struct {
+	Timestamp        time.Time         `json:"timestamp" example:"2024-01-15T10:30:00Z"`
+	EventID          string            `json:"event_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Type             string            `json:"type" example:"page_view"`
+	EventName        *string           `json:"event_name,omitempty" example:"signup"`
+	PagePath         string            `json:"page_path" example:"/pricing"`
+	PageURL          string            `json:"page_url" example:"https://example.com/pricing"`
+	Referrer         string            `json:"referrer" example:"https://google.com/"`
+	ClickOn          *string           `json:"click_on,omitempty" example:"button#buy"`
+	CustomProperties map[string]string `json:"custom_properties,omitempty"`
+}
+
+// VisitorSession is a session of a visitor
+type VisitorSession struct {
+	SessionID string    `json:"session_id" example:"8f3a2c1d9e4b7a60"`
+	StartedAt time.Time `json:"started_at" example:"2024-01-15T10:30:00Z"`
+	EndedAt   time.Time `json:"ended_at" example:"2024-01-15T10:42:00Z"`
+	PageViews uint64    `json:"page_views" example:"4"`
+	Events    uint64    `json:"events" example:"9"`
+	EntryPage string    `json:"entry_page" example:"/"`
+	ExitPage  string    `json:"exit_page" example:"/pricing"`
+}
+
+// VisitorDevice is a browser, operating system and device type combination a visitor used
+type VisitorDevice struct {
+	Browser    string    `json:"browser" example:"Chrome"`
+	OS         string    `json:"os" example:"macOS"`
+	DeviceType string    `json:"device_type" example:"desktop"`
+	Events     uint64    `json:"events" example:"42"`
+	FirstSeen  time.Time `json:"first_seen" example:"2024-01-02T08:00:00Z"`
+	LastSeen   time.Time `json:"last_seen" example:"2024-01-15T10:42:00Z"`
+}
+
+// VisitorLocation is a location a visitor was seen at
+type VisitorLocation struct {
+	Country   string    `json:"country" example:"DE"`
+	City      string    `json:"city" example:"Berlin"`
+	Events    uint64    `json:"events" example:"42"`
+	FirstSeen time.Time `json:"first_seen" example:"2024-01-02T08:00:00Z"`
+	LastSeen  time.Time `json:"last_seen" example:"2024-01-15T10:42:00Z"`
+}
+
+// VisitorResponse represents the profile of a visitor, or of every visitor linked to a user, over their whole history.
+// Sessions holds the most recent sessions, SessionCount all of them, and Timeline a page of their events in chronological order.
+type VisitorResponse struct {
+	VisitorID string `json:"visitor_id,omitempty" example:"3f2a9c1e-7b4d-4e8a-9c2f-1a2b3c4d5e6f"`
+	// UserID is the user the visitor is linked to, Traits are the latest traits of the user
+	UserID       string            `json:"user_id,omitempty" example:"user-42"`
+	Traits       map[string]any    `json:"traits,omitempty"`
+	VisitorIDs   []string          `json:"visitor_ids,omitempty"`
+	FirstSeen    time.Time         `json:"first_seen" example:"2024-01-02T08:00:00Z"`
+	LastSeen     time.Time         `json:"last_seen" example:"2024-01-15T10:42:00Z"`
+	Events       uint64            `json:"events" example:"120"`
+	PageViews    uint64            `json:"page_views" example:"64"`
+	SessionCount uint64            `json:"session_count" example:"12"`
+	Sessions     []VisitorSession  `json:"sessions"`
+	Devices      []VisitorDevice   `json:"devices"`
+	Locations    []VisitorLocation `json:"locations"`
+	Page         int               `json:"page" example:"1"`
+	Limit        int               `json:"limit" example:"50"`
+	Timeline     []VisitorEvent    `json:"timeline"`
+}
+
+// RealtimePage holds the active visitors whose last viewed page is Page
+type RealtimePage struct {
+	Page     string `json:"page" example:"/pricing"`
+	Visitors uint64 `json:"visitors" example:"12"`
+}
+
+// RealtimeResponse represents the visitors active on a project since Since
+type RealtimeResponse struct {
+	Since     time.Time      `json:"since" example:"2024-01-15T10:25:00Z"`
+	Visitors  uint64         `json:"visitors" example:"37"`
+	PageViews uint64         `json:"page_views" example:"64"`
+	Events    uint64         `json:"events" example:"95"`
+	Pages     []RealtimePage `json:"pages"`
+}
@@ -0,0 +1,48 @@
+package web
+
+import (
+	"zori/internal/server"
+	"zori/internal/server/middlewares"
+	"zori/services/analytics/services"
+)
+
+func RegisterRoutes(s *server.Server, analyticsService *services.AnalyticsService, jwtMiddleware *middlewares.JwtMiddleware) {
+	analyticsRouteGroup := s.Group("/api/v1/analytics")
+	analyticsRouteGroup.Use(jwtMiddleware.Middleware())
+
+	server.GroupStream(analyticsRouteGroup, "/live", analyticsService.LiveEvents)
+
+	server.GroupGET(analyticsRouteGroup, "/:project_id/overview", analyticsService.Overview)
+
+	server.GroupGET(analyticsRouteGroup, "/:project_id/timeseries", analyticsService.Timeseries)
+
+	server.GroupGET(analyticsRouteGroup, "/:project_id/breakdown", analyticsService.Breakdown)
+
+	server.GroupGET(analyticsRouteGroup, "/:project_id/sessions", analyticsService.Sessions)
+
+	server.GroupGET(analyticsRouteGroup, "/:project_id/realtime", analyticsService.Realtime)
+
+	server.GroupGET(analyticsRouteGroup, "/:project_id/visitors/:visitor_id", analyticsService.Visitor)
+
+	server.GroupGET(analyticsRouteGroup, "/:project_id/users/:user_id", analyticsService.User)
+
+	server.GroupGET(analyticsRouteGroup, "/:project_id/heatmap", analyticsService.Heatmap)
+
+	server.GroupGET(analyticsRouteGroup, "/:project_id/retention", analyticsService.Retention)
+
+	server.GroupGET(analyticsRouteGroup, "/:project_id/paths", analyticsService.Paths)
+
+	server.GroupGET(analyticsRouteGroup, "/:project_id/goals", analyticsService.GoalConversions)
+
+	server.GroupGET(analyticsRouteGroup, "/:project_id/funnels", analyticsService.ListFunnels)
+
+	server.GroupPOST(analyticsRouteGroup, "/:project_id/funnels", analyticsService.CreateFunnel)
+
+	server.GroupGET(analyticsRouteGroup, "/:project_id/funnels/:funnel_id", analyticsService.GetFunnel)
+
+	server.GroupPUT(analyticsRouteGroup, "/:project_id/funnels/:funnel_id", analyticsService.UpdateFunnel)
+
+	server.GroupDELETE(analyticsRouteGroup, "/:project_id/funnels/:funnel_id", analyticsService.DeleteFunnel)
+
+	server.GroupGET(analyticsRouteGroup, "/:project_id/funnels/:funnel_id/report", analyticsService.FunnelReport)
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"zori/internal/natsstream"
+	"zori/services/events/types"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// DeadLetterService lets operators inspect dead-lettered events and re-drive them into the raw events stream
+type DeadLetterService struct {
+	js jetstream.JetStream
+}
+
+func NewDeadLetterService(natsStream *natsstream.Stream) *DeadLetterService {
+	err := natsStream.UpsertDeadLetterStream(natsstream.DeadLetterEventsStream, natsstream.DeadLetterEventsSubject)
+	if err != nil {
+		panic(err)
+	}
+
+	js, err := jetstream.New(natsStream.GetConnection())
+	if err != nil {
+		panic(err)
+	}
+
+	return &DeadLetterService{js: js}
+}
+
+// List returns up to limit dead-lettered events starting from the oldest one, events are left in the stream
+func (d *DeadLetterService) List(ctx context.Context, limit int) ([]*types.DeadLetterEntry, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive, got %d", limit)
+	}
+
+	stream, err := d.js.Stream(ctx, natsstream.DeadLetterEventsStream)
+	if err != nil {
+		return nil, err
+	}
+
+	consumer, err := stream.OrderedConsumer(ctx, jetstream.OrderedConsumerConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	batch, err := consumer.Fetch(limit, jetstream.FetchMaxWait(time.Second))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*types.DeadLetterEntry, 0, limit)
+	for msg := range batch.Messages() {
+		metadata, err := msg.Metadata()
+		if err != nil {
+			return nil, err
+		}
+
+		entry, err := decodeDeadLetter(metadata.Sequence.Stream, msg.Data())
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, batch.Error()
+}
+
+// Redrive publishes up to limit dead-lettered events back to the raw events stream and removes them from the dead-letter stream
+func (d *DeadLetterService) Redrive(ctx context.Context, limit int) (int, error) {
+	entries, err := d.List(ctx, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	for idx, entry := range entries {
+		if err := d.redriveEntry(ctx, entry); err != nil {
+			return idx, err
+		}
+	}
+
+	return len(entries), nil
+}
+
+// RedriveSequence re-drives a single dead-lettered event identified by its sequence in the dead-letter stream
+func (d *DeadLetterService) RedriveSequence(ctx context.Context, sequence uint64) error {
+	stream, err := d.js.Stream(ctx, natsstream.DeadLetterEventsStream)
+	if err != nil {
+		return err
+	}
+
+	msg, err := stream.GetMsg(ctx, sequence)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrMsgNotFound) {
+			return fmt.Errorf("dead-lettered event %d not found", sequence)
+		}
+		return err
+	}
+
+	entry, err := decodeDeadLetter(msg.Sequence, msg.Data)
+	if err != nil {
+		return err
+	}
+
+	return d.redriveEntry(ctx, entry)
+}
+
+// redriveEntry publishes the event with an ID derived from its dead-letter sequence, so when its deletion fails and it
+// is re-driven again within the duplicate window of the raw events stream the second copy is dropped
+func (d *DeadLetterService) redriveEntry(ctx context.Context, entry *types.DeadLetterEntry) error {
+	msgID := fmt.Sprintf("deadletter-%d", entry.Sequence)
+	if _, err := d.js.Publish(ctx, natsstream.RawEventsSubject, entry.Frame, jetstream.WithMsgID(msgID)); err != nil {
+		return fmt.Errorf("failed to re-drive dead-lettered event %d: %w", entry.Sequence, err)
+	}
+
+	stream, err := d.js.Stream(ctx, natsstream.DeadLetterEventsStream)
+	if err != nil {
+		return err
+	}
+
+	return stream.DeleteMsg(ctx, entry.Sequence)
+}
+
+func decodeDeadLetter(sequence uint64, data []byte) (*types.DeadLetterEntry, error) {
+	entry := &types.DeadLetterEntry{Sequence: sequence}
+	if err := json.Unmarshal(data, &entry.DeadLetterV1); err != nil {
+		return nil, fmt.Errorf("failed to decode dead-lettered event %d: %w", sequence, err)
+	}
+
+	return entry, nil
+}
@@ -7,18 +7,16 @@ import (
 	"fmt"
 	"log"
 	"time"
+	"zori/internal/config"
 	"zori/internal/natsstream"
 	"zori/internal/storage/clickhouse"
+	eventTypes "zori/services/events/types"
 	"zori/services/ingestion/types"
 
+	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
 )
 
-const (
-	rawEventsStream  = "events:raw"
-	rawEventsSubject = "events:raw"
-)
-
 type Processor struct {
 	natsStream *natsstream.Stream
 
@@ -28,28 +26,68 @@ type Processor struct {
 	cancelConsumer context.CancelFunc
 	ctx            context.Context
 
+	maxDeliveriesSub *nats.Subscription
+
 	clickDb *clickhouse.ClickhouseDB
 
-	stages []ProcessorStage
+	stages   []ProcessorStage
+	stageBot *StageBot
+	// identityStages process identify and alias events, which are not enriched like tracked events
+	identityStages []ProcessorStage
+
+	maxDeliver    int
+	batchSize     int
+	flushInterval time.Duration
+
+	done chan struct{}
 }
 
-func NewProcessor(natsStream *natsstream.Stream, clickDb *clickhouse.ClickhouseDB) *Processor {
-	err := natsStream.UpsertJetStream(rawEventsStream, rawEventsSubject)
+func NewProcessor(natsStream *natsstream.Stream, clickDb *clickhouse.ClickhouseDB, cfg *config.Config) *Processor {
+	err := natsStream.UpsertJetStream(natsstream.RawEventsStream, natsstream.RawEventsSubject)
+	if err != nil {
+		panic(err)
+	}
+
+	err = natsStream.UpsertDeadLetterStream(natsstream.DeadLetterEventsStream, natsstream.DeadLetterEventsSubject)
+	if err != nil {
+		panic(err)
+	}
+
+	stageBot, err := NewStageBot(cfg.BotIPRangesPath)
+	if err != nil {
+		panic(err)
+	}
+
+	stageChannel, err := NewStageChannel(cfg.ChannelSourcesPath)
+	if err != nil {
+		panic(err)
+	}
+
+	stageLocation, err := NewStageLocation(cfg.GeoIPPath, cfg.GeoIPASNPath)
 	if err != nil {
 		panic(err)
 	}
 
 	processingStages := []ProcessorStage{
-		NewStageLocation(),
+		stageLocation,
 		NewStagePage(),
 		NewStageUserAgent(),
+		stageBot,
 		NewStageReferrer(),
+		stageChannel,
+		NewStageCustomProperties(),
 	}
 
 	p := &Processor{
-		natsStream: natsStream,
-		clickDb:    clickDb,
-		stages:     processingStages,
+		natsStream:     natsStream,
+		clickDb:        clickDb,
+		stages:         processingStages,
+		stageBot:       stageBot,
+		identityStages: []ProcessorStage{NewStageTraits()},
+		maxDeliver:     cfg.EventsMaxDeliver,
+		batchSize:      cfg.EventsBatchSize,
+		flushInterval:  cfg.EventsFlushInterval,
+		done:           make(chan struct{}),
 	}
 
 	p.ctx, p.cancelConsumer = context.WithCancel(context.Background())
@@ -60,135 +98,494 @@ func NewProcessor(natsStream *natsstream.Stream, clickDb *clickhouse.ClickhouseD
 	}
 
 	p.consumerJsConnn = jsConn
-	if consumer, err := p.consumerJsConnn.Consumer(p.ctx, rawEventsStream, "event-enricher"); err != nil {
-		if errors.Is(err, jetstream.ErrConsumerNotFound) {
-			if p.consumer, err = p.consumerJsConnn.CreateConsumer(p.ctx, rawEventsStream, jetstream.ConsumerConfig{
-				Name:    "event-enricher",
-				Durable: "event-enricher",
-			}); err != nil {
-				panic(err)
-			}
-		} else {
-			panic(err)
-		}
-	} else {
-		p.consumer = consumer
+	// messages are delivered at most maxDeliver times, failed events are dead-lettered right away and messages whose ack
+	// wait keeps expiring are dead-lettered once they reach the limit.
+	// Messages stay unacked while their batch is being filled, so ack wait has to outlast the flush interval.
+	p.consumer, err = p.consumerJsConnn.CreateOrUpdateConsumer(p.ctx, natsstream.RawEventsStream, jetstream.ConsumerConfig{
+		Name:          "event-enricher",
+		Durable:       "event-enricher",
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		MaxDeliver:    p.maxDeliver,
+		AckWait:       p.flushInterval + 30*time.Second,
+		MaxAckPending: max(p.batchSize*2, 1000),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// messages whose ack wait keeps expiring reach the delivery limit without a failure being handled, the work queue
+	// keeps them until they are deleted. A queue group makes a single processor dead-letter each of them.
+	p.maxDeliveriesSub, err = p.natsStream.GetConnection().QueueSubscribe(
+		maxDeliveriesAdvisorySubject(natsstream.RawEventsStream, "event-enricher"),
+		"event-enricher",
+		p.handleMaxDeliveries,
+	)
+	if err != nil {
+		panic(err)
 	}
 
 	return p
 }
 
+// Start fetches messages in batches and writes every batch to ClickHouse at once, a batch is flushed
+// once it is full or the flush interval elapses. Messages are acked only after the batch is committed.
 func (p *Processor) Start() error {
-	_, err := p.consumer.Consume(func(msg jetstream.Msg) {
-		var eventFrame types.ClientEventFrameV1
-		if err := json.Unmarshal(msg.Data(), &eventFrame); err != nil {
-			msg.Nak()
-			return
-		}
-
-		if err := p.processEvent(&eventFrame); err != nil {
-			fmt.Println("Failed to process event", err)
-			msg.Nak()
-			return
-		}
-
-		var (
-			clickPositionX *float64
-			clickPositionY *float64
-		)
-		if eventFrame.ClickPosition != nil && len(*eventFrame.ClickPosition) > 0 {
-			pos := *eventFrame.ClickPosition
-			clickPositionX = &pos[0]
-			clickPositionY = &pos[1]
-		}
-
-		// eventModelClick := models.Event{
-		// 	IP:                     eventFrame.IP,
-		// 	VisitorID:              eventFrame.VisitorID,
-		// 	ClientGeneratedEventID: eventFrame.ClientGeneratedEventID,
-		// 	EventName:              eventFrame.EventName,
-		// 	LocationCountryISO:     eventFrame.LocationCountryISO,
-		// 	LocationCity:           eventFrame.LocationCity,
-
-		// 	ClientTimestampUTC: eventFrame.ClientTimeStampUTC,
-		// 	ServerTimestampUTC: time.Now().UTC(),
-
-		// 	UserAgent:     eventFrame.UserAgent,
-		// 	Referrer:      eventFrame.Referrer,
-		// 	UTMParameters: eventFrame.UTMParameters,
-
-		// 	ClickOn:        eventFrame.ClickOn,
-		// 	ClickPositionX: clickPositionX,
-		// 	ClickPositionY: clickPositionY,
-		// 	ProjectID:      eventFrame.ProjectID,
-		// 	OrganizationID: eventFrame.OrganizationID,
-		// }
-
-		if err := p.clickDb.Ping(context.Background()); err != nil {
-			fmt.Println(err)
-			log.Printf("Error pinging database: %v", err)
-		}
-
-		if err := p.clickDb.Db().AsyncInsert(context.Background(),
-			`INSERT INTO events (
-				ip, visitor_id, browser_name, os_name, device_type, client_generated_event_id, event_name, location_country_iso, location_city, client_timestamp_utc,
-				server_timestamp_utc, user_agent, host, page_url, page_path, referrer_url, referrer_domain, referrer_path, utm_parameters, click_on, click_position_x, click_position_y, project_id,
-				organization_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, true,
-			eventFrame.IP,
-			eventFrame.VisitorID,
-			eventFrame.BrowserName,
-			eventFrame.OsName,
-			eventFrame.DeviceType,
-			eventFrame.ClientGeneratedEventID,
-			eventFrame.EventName,
-			eventFrame.LocationCountryISO,
-			eventFrame.LocationCity,
-			eventFrame.ClientTimeStampUTC,
-			time.Now().UTC(),
-			eventFrame.UserAgent,
-			eventFrame.Host,
-			eventFrame.PageURL,
-			eventFrame.PagePath,
-			eventFrame.Referrer,
-			eventFrame.ReferredDomain,
-			eventFrame.ReferrerPath,
-			eventFrame.UTMParameters,
-			eventFrame.ClickOn,
-			clickPositionX,
-			clickPositionY,
-			eventFrame.ProjectID,
-			eventFrame.OrganizationID,
-		); err != nil {
-			log.Printf("Error inserting event: %v", err)
-			msg.Nak()
-			return
-		}
-
-		msg.Ack()
-	})
+	defer close(p.done)
 
-	return err
+	for p.ctx.Err() == nil {
+		batch, err := p.consumer.Fetch(p.batchSize, jetstream.FetchMaxWait(p.flushInterval))
+		if err != nil {
+			if p.ctx.Err() != nil {
+				return nil
+			}
+			log.Printf("Error fetching events: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		pendingEvents := make([]*pendingEvent, 0, p.batchSize)
+		for msg := range batch.Messages() {
+			var eventFrame types.ClientEventFrameV1
+			if err := json.Unmarshal(msg.Data(), &eventFrame); err != nil {
+				p.handleFailure(msg, eventTypes.DeadLetterStageDecode, err)
+				continue
+			}
+
+			stage, err := p.processEvent(&eventFrame)
+			if errors.Is(err, ErrEventDropped) {
+				if err := msg.Ack(); err != nil {
+					log.Printf("Error acking dropped event: %v", err)
+				}
+				continue
+			}
+			if err != nil {
+				p.handleFailure(msg, stage, err)
+				continue
+			}
+
+			pendingEvents = append(pendingEvents, &pendingEvent{msg: msg, frame: &eventFrame})
+		}
+
+		if err := batch.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) {
+			log.Printf("Error receiving events batch: %v", err)
+		}
+
+		p.flush(pendingEvents)
+	}
+
+	return nil
 }
 
 func (p *Processor) Stop() error {
+	if err := p.maxDeliveriesSub.Unsubscribe(); err != nil {
+		log.Printf("Error unsubscribing from max deliveries advisories: %v", err)
+	}
 	p.cancelConsumer()
+	// wait for the batch in flight to be committed before closing the connection it has to be acked through
+	<-p.done
 	p.consumerJsConnn.Conn().Close()
 	return nil
 }
 
-func (p *Processor) processEvent(eventFrame *types.ClientEventFrameV1) error {
-	for _, stage := range p.stages {
-		if err := stage.ProcessFrame(eventFrame); err != nil {
+// pendingEvent is a processed event waiting for its batch to be written to ClickHouse
+type pendingEvent struct {
+	msg   jetstream.Msg
+	frame *types.ClientEventFrameV1
+}
+
+// flush writes the events with a single native batch insert and acks their messages once the batch is sent,
+// identify and alias events are written to the identities table in a batch of their own. Traits of identify
+// events are written to the user_traits table first, events whose traits fail are not linked either.
+func (p *Processor) flush(pendingEvents []*pendingEvent) {
+	p.storeBotVisitors()
+
+	if len(pendingEvents) == 0 {
+		return
+	}
+
+	trackedEvents := make([]*pendingEvent, 0, len(pendingEvents))
+	var identityEvents []*pendingEvent
+	for _, event := range pendingEvents {
+		if event.frame.IsIdentity() {
+			identityEvents = append(identityEvents, event)
+			continue
+		}
+		trackedEvents = append(trackedEvents, event)
+	}
+
+	serverTimestamp := time.Now().UTC()
+
+	var traitEvents []*pendingEvent
+	linkEvents := make([]*pendingEvent, 0, len(identityEvents))
+	for _, event := range identityEvents {
+		if event.frame.TraitsJSON != "" {
+			traitEvents = append(traitEvents, event)
+			continue
+		}
+		linkEvents = append(linkEvents, event)
+	}
+
+	storedEvents := p.insertBatch(insertEventsQuery, trackedEvents, eventValues, serverTimestamp, pendingEvents)
+	linkEvents = append(linkEvents, p.insertBatch(insertUserTraitsQuery, traitEvents, userTraitsValues, serverTimestamp, pendingEvents)...)
+	storedIdentities := p.insertBatch(insertIdentitiesQuery, linkEvents, identityValues, serverTimestamp, pendingEvents)
+
+	for _, event := range append(storedEvents, storedIdentities...) {
+		if err := event.msg.Ack(); err != nil {
+			log.Printf("Error acking event: %v", err)
+		}
+	}
+
+	p.publishEnriched(storedEvents)
+}
+
+// maxInsertBackoff bounds the wait between attempts to write a batch while ClickHouse is unavailable,
+// it stays below the ack wait so the messages of the batch are kept in progress in time
+const maxInsertBackoff = 30 * time.Second
+
+// insertBatch writes the events with the insert query and returns the events of the committed batch, events which
+// cannot be appended are handed to handleFailure. While ClickHouse is unavailable the batch is retried with a backoff
+// instead of being dead-lettered, fetching pauses meanwhile and the in flight messages are kept from being redelivered.
+// Once the processor is stopped the events are left for redelivery.
+func (p *Processor) insertBatch(
+	query string,
+	pendingEvents []*pendingEvent,
+	values func(*types.ClientEventFrameV1, time.Time) []any,
+	serverTimestamp time.Time,
+	inFlight []*pendingEvent,
+) []*pendingEvent {
+	for backoff := time.Second; len(pendingEvents) > 0; backoff = min(backoff*2, maxInsertBackoff) {
+		var err error
+		pendingEvents, err = p.sendBatch(query, pendingEvents, values, serverTimestamp)
+		if err == nil {
+			return pendingEvents
+		}
+
+		log.Printf("Error inserting events batch, retrying in %s: %v", backoff, err)
+		if !p.waitForRetry(inFlight, backoff) {
+			for _, event := range pendingEvents {
+				event.msg.Nak()
+			}
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// sendBatch writes the events in a single native batch, the events which could be appended are returned
+// along with the error of preparing or sending the batch
+func (p *Processor) sendBatch(
+	query string,
+	pendingEvents []*pendingEvent,
+	values func(*types.ClientEventFrameV1, time.Time) []any,
+	serverTimestamp time.Time,
+) ([]*pendingEvent, error) {
+	// the batch is not bound to the consumer context so a shutdown does not abort an insert in progress
+	batch, err := p.clickDb.Db().PrepareBatch(context.Background(), query)
+	if err != nil {
+		return pendingEvents, err
+	}
+
+	appendedEvents := make([]*pendingEvent, 0, len(pendingEvents))
+	for _, event := range pendingEvents {
+		if err := batch.Append(values(event.frame, serverTimestamp)...); err != nil {
+			log.Printf("Error appending event to batch: %v", err)
+			p.handleFailure(event.msg, eventTypes.DeadLetterStageClickhouse, err)
+			continue
+		}
+		appendedEvents = append(appendedEvents, event)
+	}
+
+	if len(appendedEvents) == 0 {
+		return nil, batch.Abort()
+	}
+
+	return appendedEvents, batch.Send()
+}
+
+// waitForRetry keeps the in flight messages from being redelivered while waiting for the backoff,
+// it returns false once the processor is stopped
+func (p *Processor) waitForRetry(inFlight []*pendingEvent, backoff time.Duration) bool {
+	for _, event := range inFlight {
+		// messages which were already acked or dead-lettered refuse the update
+		_ = event.msg.InProgress()
+	}
+
+	select {
+	case <-p.ctx.Done():
+		return false
+	case <-time.After(backoff):
+		return true
+	}
+}
+
+const insertBotVisitorsQuery = `INSERT INTO bot_visitors (organization_id, project_id, visitor_id, detected_at)`
+
+// storeBotVisitors writes the visitors the bot stage found sending too many events, reports leave out all events
+// of bot visitors. Visitors which fail to be written are kept for the next flush.
+func (p *Processor) storeBotVisitors() {
+	botVisitors := p.stageBot.takeBotVisitors()
+	if len(botVisitors) == 0 {
+		return
+	}
+
+	err := func() error {
+		batch, err := p.clickDb.Db().PrepareBatch(context.Background(), insertBotVisitorsQuery)
+		if err != nil {
 			return err
 		}
+
+		for _, visitor := range botVisitors {
+			if err := batch.Append(visitor.OrganizationID, visitor.ProjectID, visitor.VisitorID, visitor.DetectedAt); err != nil {
+				batch.Abort()
+				return err
+			}
+		}
+
+		return batch.Send()
+	}()
+	if err != nil {
+		log.Printf("Error inserting bot visitors: %v", err)
+		p.stageBot.returnBotVisitors(botVisitors)
+	}
+}
+
+// publishEnriched publishes the stored events for live subscribers, it is best effort since the events are already stored.
+// Events of bots are left out like they are left out of reports.
+func (p *Processor) publishEnriched(events []*pendingEvent) {
+	nc := p.natsStream.GetConnection()
+	for _, event := range events {
+		if event.frame.IsBot {
+			continue
+		}
+
+		eventBytes, err := json.Marshal(event.frame)
+		if err != nil {
+			log.Printf("Error encoding enriched event: %v", err)
+			continue
+		}
+
+		subject := natsstream.EnrichedEventsProjectSubject(event.frame.OrganizationID, event.frame.ProjectID)
+		if err := nc.Publish(subject, eventBytes); err != nil {
+			log.Printf("Error publishing enriched event: %v", err)
+		}
+	}
+}
+
+const insertEventsQuery = `INSERT INTO events (
+	ip, visitor_id, browser_name, os_name, device_type, is_bot, client_generated_event_id, event_name, location_country_iso, location_city, client_timestamp_utc,
+	server_timestamp_utc, user_agent, host, page_url, page_path, referrer_url, referrer_domain, referrer_path, channel, source_name, utm_parameters, click_on, click_position_x, click_position_y, page_position_x,
+	page_position_y, project_id, organization_id, custom_properties, custom_properties_map, viewport_width, viewport_height, location_region_iso, location_region, location_postal_code,
+	location_latitude, location_longitude, location_timezone, location_city_names, location_region_names, asn, as_organization)`
+
+// eventValues returns the column values of the event in the order of insertEventsQuery
+func eventValues(eventFrame *types.ClientEventFrameV1, serverTimestamp time.Time) []any {
+	clickPositionX, clickPositionY := coordinates(eventFrame.ClickPosition)
+	pagePositionX, pagePositionY := coordinates(eventFrame.PagePosition)
+
+	return []any{
+		eventFrame.IP,
+		eventFrame.VisitorID,
+		eventFrame.BrowserName,
+		eventFrame.OsName,
+		eventFrame.DeviceType,
+		eventFrame.IsBot,
+		eventFrame.ClientGeneratedEventID,
+		eventFrame.EventName,
+		eventFrame.LocationCountryISO,
+		eventFrame.LocationCity,
+		eventFrame.ClientTimeStampUTC,
+		serverTimestamp,
+		eventFrame.UserAgent,
+		eventFrame.Host,
+		eventFrame.PageURL,
+		eventFrame.PagePath,
+		eventFrame.Referrer,
+		eventFrame.ReferredDomain,
+		eventFrame.ReferrerPath,
+		eventFrame.Channel,
+		eventFrame.SourceName,
+		eventFrame.UTMParameters,
+		eventFrame.ClickOn,
+		clickPositionX,
+		clickPositionY,
+		pagePositionX,
+		pagePositionY,
+		eventFrame.ProjectID,
+		eventFrame.OrganizationID,
+		eventFrame.CustomPropertiesJSON,
+		eventFrame.CustomPropertiesMap,
+		viewportSize(eventFrame.ViewportWidth),
+		viewportSize(eventFrame.ViewportHeight),
+		eventFrame.LocationRegionISO,
+		eventFrame.LocationRegion,
+		eventFrame.LocationPostalCode,
+		eventFrame.LocationLatitude,
+		eventFrame.LocationLongitude,
+		eventFrame.LocationTimezone,
+		eventFrame.LocationCityNames,
+		eventFrame.LocationRegionNames,
+		eventFrame.ASN,
+		eventFrame.ASOrganization,
+	}
+}
+
+const insertIdentitiesQuery = `INSERT INTO identities (
+	organization_id, project_id, visitor_id, user_id, identified_at, server_timestamp_utc)`
+
+// identityValues returns the column values of the identify or alias event in the order of insertIdentitiesQuery,
+// aliases link their previous visitor ID instead of the visitor sending them
+func identityValues(eventFrame *types.ClientEventFrameV1, serverTimestamp time.Time) []any {
+	visitorID := eventFrame.VisitorID
+	if eventFrame.Type == types.EventTypeAlias {
+		visitorID = eventFrame.PreviousID
+	}
+
+	return []any{
+		eventFrame.OrganizationID,
+		eventFrame.ProjectID,
+		visitorID,
+		eventFrame.UserID,
+		eventFrame.ClientTimeStampUTC,
+		serverTimestamp,
+	}
+}
+
+const insertUserTraitsQuery = `INSERT INTO user_traits (
+	organization_id, project_id, user_id, traits, identified_at, server_timestamp_utc)`
+
+// userTraitsValues returns the column values of the traits of the identify event in the order of insertUserTraitsQuery
+func userTraitsValues(eventFrame *types.ClientEventFrameV1, serverTimestamp time.Time) []any {
+	return []any{
+		eventFrame.OrganizationID,
+		eventFrame.ProjectID,
+		eventFrame.UserID,
+		eventFrame.TraitsJSON,
+		eventFrame.ClientTimeStampUTC,
+		serverTimestamp,
+	}
+}
+
+// coordinates splits an [x, y] position into its columns, both are nil when the event has no position
+func coordinates(position *[]float64) (*float64, *float64) {
+	if position == nil || len(*position) < 2 {
+		return nil, nil
+	}
+
+	pos := *position
+	return &pos[0], &pos[1]
+}
+
+// viewportSize converts the viewport size to the UInt16 column type, sizes are validated at ingestion
+func viewportSize(size *int) *uint16 {
+	if size == nil {
+		return nil
+	}
+
+	value := uint16(*size)
+	return &value
+}
+
+// processEvent runs the frame through every stage, the name of the failed stage is returned along with the error
+func (p *Processor) processEvent(eventFrame *types.ClientEventFrameV1) (string, error) {
+	stages := p.stages
+	if eventFrame.IsIdentity() {
+		stages = p.identityStages
+	}
+
+	for _, stage := range stages {
+		if err := stage.ProcessFrame(eventFrame); err != nil {
+			return stage.Name(), err
+		}
+	}
+
+	return "", nil
+}
+
+// handleFailure moves the message to the dead-letter stream so poison messages do not block the consumer forever,
+// it is redelivered when the dead-letter stream cannot take it
+func (p *Processor) handleFailure(msg jetstream.Msg, stage string, cause error) {
+	var deliveries uint64
+	if metadata, err := msg.Metadata(); err == nil {
+		deliveries = metadata.NumDelivered
+	}
+
+	if err := p.deadLetter(msg.Data(), stage, cause, deliveries); err != nil {
+		log.Printf("Error dead-lettering event: %v", err)
+		msg.Nak()
+		return
+	}
+
+	log.Printf("Event moved to dead-letter stream, stage %s: %v", stage, cause)
+	msg.Term()
+}
+
+// deadLetter publishes the raw frame to the dead-letter stream along with the cause of the failure
+func (p *Processor) deadLetter(frame []byte, stage string, cause error, deliveries uint64) error {
+	deadLetter := eventTypes.DeadLetterV1{
+		Frame:      frame,
+		Reason:     cause.Error(),
+		Stage:      stage,
+		Deliveries: deliveries,
+		FailedAt:   time.Now().UTC(),
 	}
 
-	b, err := json.Marshal(eventFrame)
+	deadLetterBytes, err := json.Marshal(&deadLetter)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println(string(b))
+	_, err = p.consumerJsConnn.Publish(p.ctx, natsstream.DeadLetterEventsSubject, deadLetterBytes)
+	return err
+}
 
-	return nil
+// maxDeliveriesAdvisory is published by JetStream when a message reaches the delivery limit of a consumer
+type maxDeliveriesAdvisory struct {
+	StreamSeq  uint64 `json:"stream_seq"`
+	Deliveries uint64 `json:"deliveries"`
+}
+
+func maxDeliveriesAdvisorySubject(stream string, consumer string) string {
+	return "$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES." + stream + "." + consumer
+}
+
+// handleMaxDeliveries moves a message which reached the delivery limit to the dead-letter stream and deletes it from
+// the raw events stream, the work queue would otherwise keep it until the stream is full and rejects new events
+func (p *Processor) handleMaxDeliveries(advisoryMsg *nats.Msg) {
+	var advisory maxDeliveriesAdvisory
+	if err := json.Unmarshal(advisoryMsg.Data, &advisory); err != nil {
+		log.Printf("Error decoding max deliveries advisory: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(p.ctx, 10*time.Second)
+	defer cancel()
+
+	stream, err := p.consumerJsConnn.Stream(ctx, natsstream.RawEventsStream)
+	if err != nil {
+		log.Printf("Error getting raw events stream: %v", err)
+		return
+	}
+
+	msg, err := stream.GetMsg(ctx, advisory.StreamSeq)
+	if err != nil {
+		// the message was acked or deleted since
+		if !errors.Is(err, jetstream.ErrMsgNotFound) {
+			log.Printf("Error getting event %d which reached the delivery limit: %v", advisory.StreamSeq, err)
+		}
+		return
+	}
+
+	cause := fmt.Errorf("event was not acked within %d deliveries", advisory.Deliveries)
+	if err := p.deadLetter(msg.Data, eventTypes.DeadLetterStageDelivery, cause, advisory.Deliveries); err != nil {
+		log.Printf("Error dead-lettering event %d: %v", advisory.StreamSeq, err)
+		return
+	}
+
+	if err := stream.DeleteMsg(ctx, advisory.StreamSeq); err != nil {
+		log.Printf("Error deleting dead-lettered event %d: %v", advisory.StreamSeq, err)
+		return
+	}
+
+	log.Printf("Event moved to dead-letter stream, stage %s: %v", eventTypes.DeadLetterStageDelivery, cause)
 }
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"zori/internal/storage/postgres/models"
+	"zori/services/ingestion/types"
+
+	"github.com/medama-io/go-useragent/agents"
+)
+
+// botUserAgentSignatures are lowercase fragments of user agents sent by crawlers, HTTP libraries and automated
+// browsers which the user agent parser does not classify as bots
+var botUserAgentSignatures = []string{
+	"bot", "crawl", "spider", "slurp", "scrape", "pingdom", "uptime",
+	"headless", "phantomjs", "puppeteer", "playwright", "selenium", "webdriver", "lighthouse", "pagespeed",
+	"curl/", "wget/", "python-requests", "python-urllib", "aiohttp", "go-http-client", "okhttp", "axios/",
+	"node-fetch", "java/", "apache-httpclient", "libwww-perl", "httpclient", "postman",
+}
+
+// maxVisitorEventsPerMinute is the most events a person browsing sends within a minute, visitors sending more are bots
+const maxVisitorEventsPerMinute = 120
+
+//go:embed datacenter_ranges.txt
+var defaultDatacenterRanges []byte
+
+// StageBot detects events sent by bots from their user agent, datacenter IP ranges and how fast visitors send
+// events, and tags or drops them depending on the bot filter of the project. It runs after StageLocation
+// and StageUserAgent, which leave the visitor IP and the parsed device in the frame.
+//
+// A visitor is only found sending too many events once their earlier events are stored, so such visitors are
+// collected as bot visitors as well, which the processor stores for reports to leave out all of their events.
+type StageBot struct {
+	// datacenterPrefixes holds the datacenter ranges by prefix length, so an IP is matched with one lookup per length
+	datacenterPrefixes map[int]map[netip.Prefix]struct{}
+
+	now func() time.Time
+
+	mu sync.Mutex
+	// visitorEvents counts the events of visitors per minute they were sent in, it only holds the events
+	// processed since processingMinute so it does not grow with the number of visitors
+	visitorEvents    map[visitorMinute]int
+	processingMinute time.Time
+	// botVisitors are the visitors found sending too many events which are not stored yet
+	botVisitors []*BotVisitor
+}
+
+type visitorMinute struct {
+	projectID string
+	visitorID string
+	minute    time.Time
+}
+
+// BotVisitor is a visitor found to be a bot after some of their events were stored as sent by a person
+type BotVisitor struct {
+	OrganizationID string
+	ProjectID      string
+	VisitorID      string
+	DetectedAt     time.Time
+}
+
+// NewStageBot loads the datacenter IP ranges from the file at ipRangesPath, one CIDR per line with # comments.
+// The embedded ranges are loaded when the path is empty.
+func NewStageBot(ipRangesPath string) (*StageBot, error) {
+	stage := &StageBot{
+		datacenterPrefixes: make(map[int]map[netip.Prefix]struct{}),
+		now:                time.Now,
+		visitorEvents:      make(map[visitorMinute]int),
+	}
+
+	if ipRangesPath == "" {
+		return stage, stage.loadDatacenterRanges(bytes.NewReader(defaultDatacenterRanges), "the embedded ranges")
+	}
+
+	file, err := os.Open(ipRangesPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if err := stage.loadDatacenterRanges(file, ipRangesPath); err != nil {
+		return nil, err
+	}
+
+	return stage, nil
+}
+
+// loadDatacenterRanges adds the ranges of the reader, source names it in errors
+func (s *StageBot) loadDatacenterRanges(ranges io.Reader, source string) error {
+	scanner := bufio.NewScanner(ranges)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(line)
+		if err != nil {
+			return fmt.Errorf("invalid IP range on line %d of %s: %w", lineNumber, source, err)
+		}
+		s.addDatacenterPrefix(prefix)
+	}
+
+	return scanner.Err()
+}
+
+func (s *StageBot) addDatacenterPrefix(prefix netip.Prefix) {
+	prefix = prefix.Masked()
+	if prefix.Addr().Is4In6() {
+		prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+	}
+
+	prefixes, ok := s.datacenterPrefixes[prefix.Bits()]
+	if !ok {
+		prefixes = make(map[netip.Prefix]struct{})
+		s.datacenterPrefixes[prefix.Bits()] = prefixes
+	}
+	prefixes[prefix] = struct{}{}
+}
+
+func (s *StageBot) Name() string {
+	return "bot"
+}
+
+// ProcessFrame for StageBot sets IsBot on events of bots, or returns ErrEventDropped when the project drops them
+func (s *StageBot) ProcessFrame(event *types.ClientEventFrameV1) error {
+	if event.BotFilter == models.BotFilterOff {
+		return nil
+	}
+
+	event.IsBot = s.isBot(event)
+
+	if event.IsBot && event.BotFilter == models.BotFilterDrop {
+		return ErrEventDropped
+	}
+
+	return nil
+}
+
+func (s *StageBot) isBot(event *types.ClientEventFrameV1) bool {
+	if event.DeviceType != nil && *event.DeviceType == string(agents.DeviceBot) {
+		return true
+	}
+
+	// the event is counted first so visitors keep being counted once they are detected
+	return s.exceedsEventRate(event) || isBotUserAgent(event.UserAgent) || s.isDatacenterIP(event.IP)
+}
+
+// isBotUserAgent reports user agents matching a bot signature, along with the ones no browser sends:
+// every browser the tracker runs in sends a user agent starting with Mozilla/
+func isBotUserAgent(userAgent string) bool {
+	if !strings.HasPrefix(userAgent, "Mozilla/") {
+		return true
+	}
+
+	userAgent = strings.ToLower(userAgent)
+	for _, signature := range botUserAgentSignatures {
+		if strings.Contains(userAgent, signature) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *StageBot) isDatacenterIP(ip string) bool {
+	if len(s.datacenterPrefixes) == 0 {
+		return false
+	}
+
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for bits, prefixes := range s.datacenterPrefixes {
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		if _, ok := prefixes[prefix]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// exceedsEventRate counts the event for its visitor and reports visitors sending more than maxVisitorEventsPerMinute
+// within a minute, which is the minute of the client timestamp so a backlog processed at once is not mistaken for one.
+// The event going over the rate adds its visitor to the bot visitors.
+func (s *StageBot) exceedsEventRate(event *types.ClientEventFrameV1) bool {
+	processingMinute := s.now().Truncate(time.Minute)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !processingMinute.Equal(s.processingMinute) {
+		s.visitorEvents = make(map[visitorMinute]int)
+		s.processingMinute = processingMinute
+	}
+
+	key := visitorMinute{
+		projectID: event.ProjectID,
+		visitorID: event.VisitorID,
+		minute:    event.ClientTimeStampUTC.Truncate(time.Minute),
+	}
+	s.visitorEvents[key]++
+
+	if s.visitorEvents[key] == maxVisitorEventsPerMinute+1 {
+		s.botVisitors = append(s.botVisitors, &BotVisitor{
+			OrganizationID: event.OrganizationID,
+			ProjectID:      event.ProjectID,
+			VisitorID:      event.VisitorID,
+			DetectedAt:     s.now().UTC(),
+		})
+	}
+
+	return s.visitorEvents[key] > maxVisitorEventsPerMinute
+}
+
+// takeBotVisitors returns the bot visitors found since the last call
+func (s *StageBot) takeBotVisitors() []*BotVisitor {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	botVisitors := s.botVisitors
+	s.botVisitors = nil
+	return botVisitors
+}
+
+// returnBotVisitors hands back bot visitors which could not be stored, so they are taken again with the next ones
+func (s *StageBot) returnBotVisitors(botVisitors []*BotVisitor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.botVisitors = append(botVisitors, s.botVisitors...)
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"zori/internal/storage/postgres/models"
+	"zori/services/ingestion/types"
+)
+
+const chromeUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+func newTestStageBot(t *testing.T, ipRanges string) *StageBot {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "ranges.txt")
+	if err := os.WriteFile(path, []byte(ipRanges), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	stage, err := NewStageBot(path)
+	if err != nil {
+		t.Fatalf("Failed to load IP ranges: %v", err)
+	}
+	return stage
+}
+
+func botTestFrame(userAgent string, ip string) *types.ClientEventFrameV1 {
+	return &types.ClientEventFrameV1{
+		ClientEventV1: &types.ClientEventV1{
+			VisitorID:          "visitor",
+			UserAgent:          userAgent,
+			IP:                 ip,
+			ClientTimeStampUTC: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+		ProjectID: "project",
+		BotFilter: models.BotFilterTag,
+	}
+}
+
+func TestStageBotDetection(t *testing.T) {
+	stage := newTestStageBot(t, "# datacenter\n203.0.113.0/24\n2001:db8::/32 # v6\n")
+
+	tests := []struct {
+		name      string
+		userAgent string
+		ip        string
+		want      bool
+	}{
+		{"browser", chromeUserAgent, "198.51.100.7", false},
+		{"crawler", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", "198.51.100.7", true},
+		{"headless browser", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/120.0.0.0 Safari/537.36", "198.51.100.7", true},
+		{"http library", "python-requests/2.31.0", "198.51.100.7", true},
+		{"missing user agent", "", "198.51.100.7", true},
+		{"datacenter IPv4", chromeUserAgent, "203.0.113.50", true},
+		{"datacenter IPv6", chromeUserAgent, "2001:db8::1", true},
+		{"mapped datacenter IPv4", chromeUserAgent, "::ffff:203.0.113.50", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := botTestFrame(tt.userAgent, tt.ip)
+			if err := stage.ProcessFrame(event); err != nil {
+				t.Fatalf("Expected tagged events to be kept, got %v", err)
+			}
+			if event.IsBot != tt.want {
+				t.Errorf("Expected IsBot %v, got %v", tt.want, event.IsBot)
+			}
+		})
+	}
+}
+
+func TestStageBotFilters(t *testing.T) {
+	stage := newTestStageBot(t, "")
+
+	event := botTestFrame("curl/8.4.0", "198.51.100.7")
+	event.BotFilter = models.BotFilterDrop
+	if err := stage.ProcessFrame(event); !errors.Is(err, ErrEventDropped) {
+		t.Errorf("Expected bots to be dropped, got %v", err)
+	}
+
+	event = botTestFrame(chromeUserAgent, "198.51.100.7")
+	event.BotFilter = models.BotFilterDrop
+	if err := stage.ProcessFrame(event); err != nil {
+		t.Errorf("Expected browsers to be kept, got %v", err)
+	}
+
+	event = botTestFrame("curl/8.4.0", "198.51.100.7")
+	event.BotFilter = models.BotFilterOff
+	if err := stage.ProcessFrame(event); err != nil || event.IsBot {
+		t.Errorf("Expected detection to be off, got IsBot %v and %v", event.IsBot, err)
+	}
+}
+
+func TestStageBotEventRate(t *testing.T) {
+	stage := newTestStageBot(t, "")
+	stage.now = func() time.Time { return time.Date(2024, 1, 1, 12, 0, 30, 0, time.UTC) }
+
+	for i := 0; i < maxVisitorEventsPerMinute; i++ {
+		event := botTestFrame(chromeUserAgent, "198.51.100.7")
+		stage.ProcessFrame(event)
+		if event.IsBot {
+			t.Fatalf("Expected event %d to be within the rate", i+1)
+		}
+	}
+
+	event := botTestFrame(chromeUserAgent, "198.51.100.7")
+	stage.ProcessFrame(event)
+	if !event.IsBot {
+		t.Error("Expected visitors sending too many events to be detected")
+	}
+
+	botVisitors := stage.takeBotVisitors()
+	if len(botVisitors) != 1 || botVisitors[0].VisitorID != "visitor" || botVisitors[0].ProjectID != "project" {
+		t.Fatalf("Expected the visitor to be collected once as a bot visitor, got %v", botVisitors)
+	}
+
+	event = botTestFrame(chromeUserAgent, "198.51.100.7")
+	stage.ProcessFrame(event)
+	if !event.IsBot || len(stage.takeBotVisitors()) != 0 {
+		t.Error("Expected further events to be detected without collecting the visitor again")
+	}
+
+	stage.returnBotVisitors(botVisitors)
+	if len(stage.takeBotVisitors()) != 1 {
+		t.Error("Expected bot visitors which failed to be stored to be taken again")
+	}
+
+	event = botTestFrame(chromeUserAgent, "198.51.100.7")
+	event.ClientTimeStampUTC = event.ClientTimeStampUTC.Add(time.Minute)
+	stage.ProcessFrame(event)
+	if event.IsBot {
+		t.Error("Expected events of the next minute to be counted on their own")
+	}
+}
+
+func TestNewStageBotEmbeddedRanges(t *testing.T) {
+	stage, err := NewStageBot("")
+	if err != nil {
+		t.Fatalf("Failed to load the embedded ranges: %v", err)
+	}
+
+	if !stage.isDatacenterIP("159.89.10.20") {
+		t.Error("Expected the embedded ranges to detect datacenter IPs")
+	}
+	if stage.isDatacenterIP("198.51.100.7") {
+		t.Error("Expected IPs outside the embedded ranges not to be detected")
+	}
+}
+
+func TestNewStageBotInvalidRange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ranges.txt")
+	if err := os.WriteFile(path, []byte("10.0.0.0/8\nnot a range\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewStageBot(path); err == nil {
+		t.Error("Expected an invalid range to fail loading")
+	}
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"zori/services/ingestion/types"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Channels traffic is classified into
+const (
+	ChannelOrganicSearch = "organic_search"
+	ChannelPaidSearch    = "paid_search"
+	ChannelSocial        = "social"
+	ChannelEmail         = "email"
+	ChannelReferral      = "referral"
+	ChannelDirect        = "direct"
+)
+
+// Categories of traffic sources in the source list
+const (
+	sourceCategorySearch = "search"
+	sourceCategorySocial = "social"
+	sourceCategoryEmail  = "email"
+)
+
+//go:embed channel_sources.json
+var defaultChannelSources []byte
+
+// channelSources is the source list traffic is classified with
+type channelSources struct {
+	Sources []*channelSource `json:"sources"`
+	// ClickIDs are checked in order, the first one found in the page URL wins
+	ClickIDs []*clickID `json:"click_ids"`
+}
+
+type channelSource struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	// Domains match the host and its subdomains, a domain ending in .* matches the name under any top level domain
+	Domains []string `json:"domains"`
+	// UTMSources are lowercase utm_source values which refer to the source
+	UTMSources []string `json:"utm_sources"`
+}
+
+// clickID is a query parameter ad and social platforms add to the links they send visitors through
+type clickID struct {
+	Param  string `json:"param"`
+	Source string `json:"source"`
+	// Paid click IDs are only added to clicks on ads
+	Paid bool `json:"paid"`
+}
+
+// utm_medium values of campaigns, lowercase
+var (
+	paidSearchMediums = []string{"cpc", "ppc", "paid", "paidsearch", "paid_search", "paid-search", "sem"}
+	emailMediums      = []string{"email", "e-mail", "e_mail", "newsletter"}
+	organicMediums    = []string{"organic", "organic_search"}
+)
+
+// StageChannel classifies the traffic of events into channels and names its source from click IDs and UTM
+// parameters of the page URL and the referrer domain. It runs after StageReferrer, which leaves the referrer
+// domain in the frame.
+type StageChannel struct {
+	// domains holds the sources by domain, wildcardDomains the ones matched under any top level domain by name
+	domains         map[string]*channelSource
+	wildcardDomains map[string]*channelSource
+	utmSources      map[string]*channelSource
+	sourcesByName   map[string]*channelSource
+	clickIDs        []*clickID
+}
+
+// NewStageChannel loads the source list from the JSON file at sourcesPath, the embedded list is used when it is empty
+func NewStageChannel(sourcesPath string) (*StageChannel, error) {
+	sourcesJSON := defaultChannelSources
+	if sourcesPath != "" {
+		var err error
+		if sourcesJSON, err = os.ReadFile(sourcesPath); err != nil {
+			return nil, err
+		}
+	}
+
+	var sources channelSources
+	if err := json.Unmarshal(sourcesJSON, &sources); err != nil {
+		return nil, fmt.Errorf("invalid channel sources: %w", err)
+	}
+
+	stage := &StageChannel{
+		domains:         make(map[string]*channelSource),
+		wildcardDomains: make(map[string]*channelSource),
+		utmSources:      make(map[string]*channelSource),
+		sourcesByName:   make(map[string]*channelSource),
+		clickIDs:        sources.ClickIDs,
+	}
+
+	for _, source := range sources.Sources {
+		stage.sourcesByName[source.Name] = source
+
+		for _, domain := range source.Domains {
+			domain = strings.ToLower(domain)
+			if name, ok := strings.CutSuffix(domain, ".*"); ok {
+				stage.wildcardDomains[name] = source
+				continue
+			}
+			stage.domains[domain] = source
+		}
+
+		for _, utmSource := range source.UTMSources {
+			stage.utmSources[strings.ToLower(utmSource)] = source
+		}
+	}
+
+	for _, id := range sources.ClickIDs {
+		if _, ok := stage.sourcesByName[id.Source]; !ok {
+			return nil, fmt.Errorf("click ID %s refers to unknown source %s", id.Param, id.Source)
+		}
+	}
+
+	return stage, nil
+}
+
+func (s *StageChannel) Name() string {
+	return "channel"
+}
+
+// ProcessFrame for StageChannel sets the channel and source name of the event
+func (s *StageChannel) ProcessFrame(event *types.ClientEventFrameV1) error {
+	var query url.Values
+	pageHost := ""
+	if parsedPageURL, err := url.Parse(event.PageURL); err == nil {
+		query = parsedPageURL.Query()
+		pageHost = parsedPageURL.Hostname()
+	}
+
+	referrerDomain := ""
+	if event.ReferredDomain != nil {
+		referrerDomain = *event.ReferredDomain
+	}
+
+	event.Channel, event.SourceName = s.classify(query, event.UTMParameters, referrerDomain, pageHost)
+	return nil
+}
+
+// classify returns the channel and source name of a visit. Paid click IDs take precedence over UTM parameters,
+// which take precedence over other click IDs and the referrer. Navigation within the site counts as direct.
+func (s *StageChannel) classify(query url.Values, utmParameters map[string]string, referrerDomain string, pageHost string) (string, string) {
+	utmSource := strings.ToLower(utmParameter(query, utmParameters, "utm_source"))
+	utmMedium := strings.ToLower(utmParameter(query, utmParameters, "utm_medium"))
+
+	var clickSource *channelSource
+	for _, id := range s.clickIDs {
+		if query.Get(id.Param) == "" {
+			continue
+		}
+		clickSource = s.sourcesByName[id.Source]
+		if id.Paid {
+			return ChannelPaidSearch, clickSource.Name
+		}
+		break
+	}
+
+	referrerHost := normalizeHost(referrerDomain)
+	if referrerHost != "" && referrerHost == normalizeHost(pageHost) {
+		referrerHost = ""
+	}
+	referrerSource := s.sourceByDomain(referrerHost)
+
+	source := s.utmSources[utmSource]
+	if source == nil {
+		source = clickSource
+	}
+	if source == nil {
+		source = referrerSource
+	}
+
+	sourceName := utmSource
+	switch {
+	case source != nil:
+		sourceName = source.Name
+	case sourceName == "":
+		sourceName = referrerHost
+	}
+
+	switch {
+	case slices.Contains(paidSearchMediums, utmMedium):
+		// ads on social networks are social traffic, there is no paid social channel
+		if source != nil && source.Category == sourceCategorySocial {
+			return ChannelSocial, sourceName
+		}
+		return ChannelPaidSearch, sourceName
+	case slices.Contains(emailMediums, utmMedium):
+		return ChannelEmail, sourceName
+	case strings.Contains(utmMedium, "social"):
+		return ChannelSocial, sourceName
+	case slices.Contains(organicMediums, utmMedium):
+		return ChannelOrganicSearch, sourceName
+	}
+
+	if source != nil {
+		switch source.Category {
+		case sourceCategorySearch:
+			return ChannelOrganicSearch, sourceName
+		case sourceCategorySocial:
+			return ChannelSocial, sourceName
+		case sourceCategoryEmail:
+			return ChannelEmail, sourceName
+		}
+	}
+
+	if sourceName == "" {
+		return ChannelDirect, ""
+	}
+
+	return ChannelReferral, sourceName
+}
+
+// sourceByDomain returns the source of the host, the most specific domain wins over shorter ones and wildcards
+func (s *StageChannel) sourceByDomain(host string) *channelSource {
+	if host == "" {
+		return nil
+	}
+
+	domain := host
+	for {
+		if source, ok := s.domains[domain]; ok {
+			return source
+		}
+
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			break
+		}
+		domain = parent
+	}
+
+	labels := strings.Split(host, ".")
+	for idx, label := range labels {
+		source, ok := s.wildcardDomains[label]
+		if !ok {
+			continue
+		}
+
+		// only a public suffix may follow the name, e.g. google.com or google.co.uk but not google.example.com
+		suffix := strings.Join(labels[idx+1:], ".")
+		if publicSuffix, icann := publicsuffix.PublicSuffix(suffix); icann && publicSuffix == suffix {
+			return source
+		}
+	}
+
+	return nil
+}
+
+// utmParameter returns the UTM parameter sent with the event, or the one of the page URL when there is none
+func utmParameter(query url.Values, utmParameters map[string]string, name string) string {
+	if value := utmParameters[name]; value != "" {
+		return value
+	}
+	return query.Get(name)
+}
+
+func normalizeHost(host string) string {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	return strings.TrimPrefix(host, "www.")
+}
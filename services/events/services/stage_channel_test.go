@@ -0,0 +1,87 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"zori/services/ingestion/types"
+)
+
+func TestStageChannelClassify(t *testing.T) {
+	stage, err := NewStageChannel("")
+	if err != nil {
+		t.Fatalf("Failed to load the embedded sources: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		pageURL        string
+		utmParameters  map[string]string
+		referrerDomain string
+		wantChannel    string
+		wantSource     string
+	}{
+		{"direct", "https://example.com/", nil, "", ChannelDirect, ""},
+		{"internal navigation", "https://example.com/pricing", nil, "www.example.com", ChannelDirect, ""},
+		{"organic search", "https://example.com/", nil, "www.google.co.uk", ChannelOrganicSearch, "Google"},
+		{"search subdomain", "https://example.com/", nil, "search.yahoo.com", ChannelOrganicSearch, "Yahoo"},
+		{"more specific domain", "https://example.com/", nil, "mail.google.com", ChannelEmail, "Gmail"},
+		{"not a search engine", "https://example.com/", nil, "google.example.com", ChannelReferral, "google.example.com"},
+		{"short second level domain", "https://example.com/", nil, "google.foo.com", ChannelReferral, "google.foo.com"},
+		{"unknown top level domain", "https://example.com/", nil, "google.invalid", ChannelReferral, "google.invalid"},
+		{"paid click ID", "https://example.com/?gclid=abc", nil, "www.google.com", ChannelPaidSearch, "Google"},
+		{"paid click ID over UTM", "https://example.com/?msclkid=abc&utm_source=newsletter", nil, "", ChannelPaidSearch, "Bing"},
+		{"social click ID", "https://example.com/?fbclid=abc", nil, "", ChannelSocial, "Facebook"},
+		{"social referrer", "https://example.com/", nil, "t.co", ChannelSocial, "X"},
+		{"paid UTM medium", "https://example.com/", map[string]string{"utm_source": "google", "utm_medium": "cpc"}, "", ChannelPaidSearch, "Google"},
+		{"paid social UTM medium", "https://example.com/?utm_source=facebook&utm_medium=cpc", nil, "", ChannelSocial, "Facebook"},
+		{"email UTM medium", "https://example.com/", map[string]string{"utm_source": "spring-launch", "utm_medium": "email"}, "", ChannelEmail, "spring-launch"},
+		{"unknown UTM source", "https://example.com/", map[string]string{"utm_source": "partner"}, "", ChannelReferral, "partner"},
+		{"referral", "https://example.com/", nil, "blog.example.org", ChannelReferral, "blog.example.org"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &types.ClientEventFrameV1{
+				ClientEventV1: &types.ClientEventV1{PageURL: tt.pageURL, UTMParameters: tt.utmParameters},
+			}
+			if tt.referrerDomain != "" {
+				event.ReferredDomain = &tt.referrerDomain
+			}
+
+			if err := stage.ProcessFrame(event); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if event.Channel != tt.wantChannel || event.SourceName != tt.wantSource {
+				t.Errorf("Expected %s from %q, got %s from %q", tt.wantChannel, tt.wantSource, event.Channel, event.SourceName)
+			}
+		})
+	}
+}
+
+func TestNewStageChannelSourcesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sources.json")
+	sources := `{"sources": [{"name": "Kagi", "category": "search", "domains": ["kagi.com"]}], "click_ids": [{"param": "kclid", "source": "Kagi", "paid": true}]}`
+	if err := os.WriteFile(path, []byte(sources), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	stage, err := NewStageChannel(path)
+	if err != nil {
+		t.Fatalf("Failed to load the sources file: %v", err)
+	}
+
+	if channel, source := stage.classify(nil, nil, "kagi.com", "example.com"); channel != ChannelOrganicSearch || source != "Kagi" {
+		t.Errorf("Expected organic search from Kagi, got %s from %q", channel, source)
+	}
+	if channel, source := stage.classify(nil, nil, "www.google.com", "example.com"); channel != ChannelReferral || source != "google.com" {
+		t.Errorf("Expected the embedded sources to be replaced, got %s from %q", channel, source)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"click_ids": [{"param": "xclid", "source": "Unknown"}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewStageChannel(path); err == nil {
+		t.Error("Expected click IDs of unknown sources to fail loading")
+	}
+}
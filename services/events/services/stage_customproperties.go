@@ -0,0 +1,67 @@
+package services
+
+import (
+	"encoding/json"
+	"strconv"
+	"zori/services/ingestion/types"
+)
+
+type StageCustomProperties struct {
+}
+
+func NewStageCustomProperties() StageCustomProperties {
+	return StageCustomProperties{}
+}
+
+func (s StageCustomProperties) Name() string {
+	return "custom_properties"
+}
+
+// ProcessFrame for StageCustomProperties serializes custom properties and flattens them into a map for key indexing
+func (s StageCustomProperties) ProcessFrame(event *types.ClientEventFrameV1) error {
+	event.CustomPropertiesMap = map[string]string{}
+	if len(event.CustomProperties) == 0 {
+		event.CustomPropertiesJSON = ""
+		return nil
+	}
+
+	customPropertiesBytes, err := json.Marshal(event.CustomProperties)
+	if err != nil {
+		return err
+	}
+	event.CustomPropertiesJSON = string(customPropertiesBytes)
+
+	return flattenCustomProperties(event.CustomPropertiesMap, "", event.CustomProperties)
+}
+
+// flattenCustomProperties writes nested objects with dot separated keys, arrays are kept as JSON encoded values
+func flattenCustomProperties(flattened map[string]string, prefix string, properties map[string]any) error {
+	for key, value := range properties {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		switch v := value.(type) {
+		case nil:
+			continue
+		case string:
+			flattened[key] = v
+		case bool:
+			flattened[key] = strconv.FormatBool(v)
+		case float64:
+			flattened[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		case map[string]any:
+			if err := flattenCustomProperties(flattened, key, v); err != nil {
+				return err
+			}
+		default:
+			valueBytes, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			flattened[key] = string(valueBytes)
+		}
+	}
+
+	return nil
+}
@@ -1,7 +1,7 @@
 package services
 
 import (
-	"log"
+	"math"
 	"net/netip"
 	"strings"
 	"zori/services/ingestion/types"
@@ -12,21 +12,70 @@ import (
 
 type StageLocation struct {
 	maxMindDb *maxminddb.Reader
+	// asnDb is nil when no ASN database is configured
+	asnDb *maxminddb.Reader
 }
 
-func NewStageLocation() StageLocation {
-	maxMindDB, err := maxminddb.Open("./ipdb.mmdb")
+// cityRecord holds the fields of GeoIP2 and GeoLite2 City records the stage stores
+type cityRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	// Subdivisions go from the largest to the smallest, the first one is the region
+	Subdivisions []subdivisionRecord `maxminddb:"subdivisions"`
+	Postal       struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"postal"`
+	Location struct {
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+		TimeZone  string   `maxminddb:"time_zone"`
+	} `maxminddb:"location"`
+}
+
+type subdivisionRecord struct {
+	ISOCode string            `maxminddb:"iso_code"`
+	Names   map[string]string `maxminddb:"names"`
+}
+
+// asnRecord holds the fields of GeoLite2 ASN records
+type asnRecord struct {
+	AutonomousSystemNumber       uint32 `maxminddb:"autonomous_system_number"`
+	AutonomousSystemOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
+// NewStageLocation opens the city database at cityDbPath and the ASN database at asnDbPath, networks are not
+// looked up when asnDbPath is empty
+func NewStageLocation(cityDbPath string, asnDbPath string) (*StageLocation, error) {
+	maxMindDB, err := maxminddb.Open(cityDbPath)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	return StageLocation{
-		maxMindDb: maxMindDB,
+	stage := &StageLocation{maxMindDb: maxMindDB}
+	if asnDbPath == "" {
+		return stage, nil
 	}
+
+	stage.asnDb, err = maxminddb.Open(asnDbPath)
+	if err != nil {
+		maxMindDB.Close()
+		return nil, err
+	}
+
+	return stage, nil
+}
+
+func (s *StageLocation) Name() string {
+	return "location"
 }
 
-// ProcessFrame for StageLocation parses the IP with MaxMindDB and extracts approximate location information (city and country)
-func (s StageLocation) ProcessFrame(event *types.ClientEventFrameV1) error {
+// ProcessFrame for StageLocation parses the IP with MaxMindDB and extracts approximate location information,
+// from the country down to the city and postal code, and the network the IP belongs to
+func (s *StageLocation) ProcessFrame(event *types.ClientEventFrameV1) error {
 	if event.IP == "" {
 		return nil
 	}
@@ -41,21 +90,62 @@ func (s StageLocation) ProcessFrame(event *types.ClientEventFrameV1) error {
 			return err
 		}
 
-		var countryCode string
-		err = s.maxMindDb.Lookup(parsedIp).DecodePath(&countryCode, "country", "iso_code")
-		if err != nil {
+		var city cityRecord
+		if err := s.maxMindDb.Lookup(parsedIp).Decode(&city); err != nil {
 			return err
 		}
+		fillLocation(event, &city)
 
-		var cityName string
-		err = s.maxMindDb.Lookup(parsedIp).DecodePath(&cityName, "city", "names", "en")
-		if err != nil {
-			return err
+		if s.asnDb != nil {
+			var asn asnRecord
+			if err := s.asnDb.Lookup(parsedIp).Decode(&asn); err != nil {
+				return err
+			}
+			fillNetwork(event, &asn)
 		}
-
-		event.LocationCountryISO = nullable.FromString(countryCode).Ptr()
-		event.LocationCity = nullable.FromString(cityName).Ptr()
 	}
 
 	return nil
 }
+
+// fillLocation sets the location of the event from the city record, missing fields are left empty
+func fillLocation(event *types.ClientEventFrameV1, city *cityRecord) {
+	event.LocationCountryISO = nullable.FromString(city.Country.ISOCode).Ptr()
+	event.LocationCity = nullable.FromString(city.City.Names["en"]).Ptr()
+	event.LocationCityNames = city.City.Names
+
+	if len(city.Subdivisions) > 0 {
+		region := city.Subdivisions[0]
+		if region.ISOCode != "" && city.Country.ISOCode != "" {
+			event.LocationRegionISO = nullable.FromString(city.Country.ISOCode + "-" + region.ISOCode).Ptr()
+		}
+		event.LocationRegion = nullable.FromString(region.Names["en"]).Ptr()
+		event.LocationRegionNames = region.Names
+	}
+
+	event.LocationPostalCode = nullable.FromString(city.Postal.Code).Ptr()
+	event.LocationTimezone = nullable.FromString(city.Location.TimeZone).Ptr()
+
+	if city.Location.Latitude != nil && city.Location.Longitude != nil {
+		event.LocationLatitude = coarseCoordinate(*city.Location.Latitude)
+		event.LocationLongitude = coarseCoordinate(*city.Location.Longitude)
+	}
+}
+
+// fillNetwork sets the autonomous system of the event from the ASN record, IPs outside any are left empty
+func fillNetwork(event *types.ClientEventFrameV1, asn *asnRecord) {
+	if asn.AutonomousSystemNumber == 0 {
+		return
+	}
+
+	number := asn.AutonomousSystemNumber
+	event.ASN = &number
+	event.ASOrganization = nullable.FromString(asn.AutonomousSystemOrganization).Ptr()
+}
+
+// coarseCoordinate rounds a latitude or longitude to a tenth of a degree, so visitors are located no closer than
+// about 11km, which is all region maps need
+func coarseCoordinate(degrees float64) *float32 {
+	coarse := float32(math.Round(degrees*10) / 10)
+	return &coarse
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"testing"
+	"zori/services/ingestion/types"
+)
+
+func TestFillLocation(t *testing.T) {
+	latitude, longitude := 37.7749, -122.4194
+
+	var city cityRecord
+	city.Country.ISOCode = "US"
+	city.City.Names = map[string]string{"en": "San Francisco", "ja": "サンフランシスコ"}
+	city.Subdivisions = []subdivisionRecord{{ISOCode: "CA", Names: map[string]string{"en": "California", "de": "Kalifornien"}}}
+	city.Postal.Code = "94107"
+	city.Location.Latitude = &latitude
+	city.Location.Longitude = &longitude
+	city.Location.TimeZone = "America/Los_Angeles"
+
+	event := &types.ClientEventFrameV1{ClientEventV1: &types.ClientEventV1{}}
+	fillLocation(event, &city)
+
+	if *event.LocationCountryISO != "US" || *event.LocationCity != "San Francisco" {
+		t.Errorf("Expected San Francisco, US, got %s, %s", *event.LocationCity, *event.LocationCountryISO)
+	}
+	if *event.LocationRegionISO != "US-CA" || *event.LocationRegion != "California" {
+		t.Errorf("Expected the region US-CA California, got %s %s", *event.LocationRegionISO, *event.LocationRegion)
+	}
+	if event.LocationRegionNames["de"] != "Kalifornien" || event.LocationCityNames["ja"] != "サンフランシスコ" {
+		t.Errorf("Expected localized names, got %v and %v", event.LocationRegionNames, event.LocationCityNames)
+	}
+	if *event.LocationPostalCode != "94107" || *event.LocationTimezone != "America/Los_Angeles" {
+		t.Errorf("Expected postal code and timezone, got %s and %s", *event.LocationPostalCode, *event.LocationTimezone)
+	}
+	if *event.LocationLatitude != 37.8 || *event.LocationLongitude != -122.4 {
+		t.Errorf("Expected coordinates rounded to a tenth of a degree, got %v, %v", *event.LocationLatitude, *event.LocationLongitude)
+	}
+}
+
+func TestFillLocationWithoutDetails(t *testing.T) {
+	var city cityRecord
+	city.Country.ISOCode = "DE"
+
+	event := &types.ClientEventFrameV1{ClientEventV1: &types.ClientEventV1{}}
+	fillLocation(event, &city)
+
+	if event.LocationRegionISO != nil || event.LocationLatitude != nil || event.LocationLongitude != nil {
+		t.Errorf("Expected no region and coordinates, got %v, %v, %v", event.LocationRegionISO, event.LocationLatitude, event.LocationLongitude)
+	}
+}
+
+func TestFillNetwork(t *testing.T) {
+	event := &types.ClientEventFrameV1{ClientEventV1: &types.ClientEventV1{}}
+	fillNetwork(event, &asnRecord{AutonomousSystemNumber: 15169, AutonomousSystemOrganization: "GOOGLE"})
+
+	if event.ASN == nil || *event.ASN != 15169 || *event.ASOrganization != "GOOGLE" {
+		t.Errorf("Expected AS15169 GOOGLE, got %v %v", event.ASN, event.ASOrganization)
+	}
+
+	event = &types.ClientEventFrameV1{ClientEventV1: &types.ClientEventV1{}}
+	fillNetwork(event, &asnRecord{})
+
+	if event.ASN != nil || event.ASOrganization != nil {
+		t.Errorf("Expected IPs outside any autonomous system to have no network, got %v %v", event.ASN, event.ASOrganization)
+	}
+}
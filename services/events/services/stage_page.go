@@ -14,6 +14,10 @@ func NewStagePage() StagePage {
 	return StagePage{}
 }
 
+func (s StagePage) Name() string {
+	return "page"
+}
+
 // ProcessFrame for StagePage parses the request and splits URL into path
 func (s StagePage) ProcessFrame(event *types.ClientEventFrameV1) error {
 	if event.PageURL == "" {
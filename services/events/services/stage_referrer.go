@@ -14,6 +14,10 @@ func NewStageReferrer() StageReferrer {
 	return StageReferrer{}
 }
 
+func (s StageReferrer) Name() string {
+	return "referrer"
+}
+
 // ProcessFrame for StageReferrer processed referrer information and extracts path and domain for better indexing
 func (s StageReferrer) ProcessFrame(event *types.ClientEventFrameV1) error {
 	if event.Referrer == "" {
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"encoding/json"
+	"zori/services/ingestion/types"
+)
+
+type StageTraits struct {
+}
+
+func NewStageTraits() StageTraits {
+	return StageTraits{}
+}
+
+func (s StageTraits) Name() string {
+	return "traits"
+}
+
+// ProcessFrame for StageTraits serializes the traits of identify events
+func (s StageTraits) ProcessFrame(event *types.ClientEventFrameV1) error {
+	if len(event.Traits) == 0 {
+		event.TraitsJSON = ""
+		return nil
+	}
+
+	traitsBytes, err := json.Marshal(event.Traits)
+	if err != nil {
+		return err
+	}
+	event.TraitsJSON = string(traitsBytes)
+
+	return nil
+}
@@ -17,6 +17,10 @@ func NewStageUserAgent() ProcessorStage {
 	}
 }
 
+func (s StageUserAgent) Name() string {
+	return "user_agent"
+}
+
 // ProcessFrame for StageUserAgent parses the request user-agent header and determines OS and browser information
 func (s StageUserAgent) ProcessFrame(event *types.ClientEventFrameV1) error {
 	if event.UserAgent == "" {
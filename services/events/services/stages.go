@@ -1,7 +1,15 @@
 package services
 
-import "zori/services/ingestion/types"
+import (
+	"errors"
+	"zori/services/ingestion/types"
+)
+
+// ErrEventDropped is returned by stages for events which are discarded on purpose, they are acked without being stored
+var ErrEventDropped = errors.New("event dropped")
 
 type ProcessorStage interface {
+	// Name identifies the stage in logs and dead-lettered events
+	Name() string
 	ProcessFrame(event *types.ClientEventFrameV1) error
 }
@@ -0,0 +1,31 @@
+package types
+
+import "time"
+
+const (
+	// DeadLetterStageDecode is used when the raw frame could not be decoded
+	DeadLetterStageDecode = "decode"
+	// DeadLetterStageClickhouse is used when the enriched event could not be stored
+	DeadLetterStageClickhouse = "clickhouse"
+	// DeadLetterStageDelivery is used when the event reached the delivery limit without being acked
+	DeadLetterStageDelivery = "delivery"
+)
+
+// DeadLetterV1 represents an event the processor gave up on, it carries the original frame
+// so the event can be re-driven once the cause of the failure is fixed.
+type DeadLetterV1 struct {
+	// Frame is the original message payload exactly as it was read from the raw events stream.
+	Frame []byte `json:"frame"`
+	// Reason is the error message of the last failed attempt.
+	Reason string `json:"reason"`
+	// Stage is the name of the processor stage which failed.
+	Stage      string    `json:"stage"`
+	Deliveries uint64    `json:"deliveries"`
+	FailedAt   time.Time `json:"failed_at"`
+}
+
+// DeadLetterEntry is a dead-lettered event together with its position in the dead-letter stream.
+type DeadLetterEntry struct {
+	Sequence uint64 `json:"sequence"`
+	DeadLetterV1
+}
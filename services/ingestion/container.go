@@ -1,6 +1,9 @@
 package ingestion
 
 import (
+	"context"
+	"zori/internal/cache"
+	"zori/services/ingestion/data"
 	"zori/services/ingestion/services"
 	"zori/services/ingestion/web"
 
@@ -9,7 +12,40 @@ import (
 
 func BuildIngestionDiContainer() fx.Option {
 	return fx.Module("ingestion",
+		fx.Provide(cache.NewCache),
+		fx.Provide(services.NewProjectLookup),
+		fx.Provide(data.NewSaltData),
+		fx.Provide(services.NewVisitorIdentifier),
 		fx.Provide(services.NewIngestor),
+		fx.Provide(services.NewUsageTracker),
 		fx.Provide(web.NewIngestionServer),
+		fx.Invoke(func(lc fx.Lifecycle, ingestor *services.Ingestor) {
+			lc.Append(fx.Hook{
+				OnStop: func(ctx context.Context) error {
+					return ingestor.Drain(ctx)
+				},
+			})
+		}),
+		fx.Invoke(func(lc fx.Lifecycle, projectLookup *services.ProjectLookup) {
+			lc.Append(fx.Hook{
+				OnStart: func(ctx context.Context) error {
+					return projectLookup.Listen(ctx)
+				},
+				OnStop: func(ctx context.Context) error {
+					return projectLookup.Close()
+				},
+			})
+		}),
+		fx.Invoke(func(lc fx.Lifecycle, usageTracker *services.UsageTracker) {
+			lc.Append(fx.Hook{
+				OnStart: func(ctx context.Context) error {
+					usageTracker.Start()
+					return nil
+				},
+				OnStop: func(ctx context.Context) error {
+					return usageTracker.Stop(ctx)
+				},
+			})
+		}),
 	)
 }
@@ -0,0 +1,59 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"time"
+	"zori/internal/storage/postgres"
+	"zori/internal/storage/postgres/models"
+
+	"github.com/uptrace/bun"
+)
+
+// SaltSize is the size in bytes of generated visitor salts
+const SaltSize = 32
+
+type SaltData struct {
+	db *bun.DB
+}
+
+func NewSaltData(db *postgres.PostgresDB) *SaltData {
+	return &SaltData{db: db.DB}
+}
+
+// DailySalt returns the salt of the day, creating it when it does not exist yet. Concurrent callers
+// always agree on the salt since only the first insert of a day wins.
+func (s *SaltData) DailySalt(ctx context.Context, day time.Time) ([]byte, error) {
+	salt := make([]byte, SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	_, err := s.db.NewInsert().
+		Model(&models.VisitorSalt{Day: day, Salt: salt}).
+		On("CONFLICT (day) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	visitorSalt := &models.VisitorSalt{}
+	err = s.db.NewSelect().
+		Model(visitorSalt).
+		Where("day = ?", day).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return visitorSalt.Salt, nil
+}
+
+// DeleteSaltsBefore deletes the salts of the days before day
+func (s *SaltData) DeleteSaltsBefore(ctx context.Context, day time.Time) error {
+	_, err := s.db.NewDelete().
+		Model((*models.VisitorSalt)(nil)).
+		Where("day < ?", day).
+		Exec(ctx)
+	return err
+}
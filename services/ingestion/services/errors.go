@@ -0,0 +1,44 @@
+package services
+
+import "time"
+
+// ErrorBackpressure is returned when too many events are waiting for the stream to acknowledge them.
+type ErrorBackpressure struct{}
+
+func (e *ErrorBackpressure) Error() string {
+	return "too many events are waiting to be acknowledged by the stream"
+}
+
+func NewErrorBackpressure() *ErrorBackpressure {
+	return &ErrorBackpressure{}
+}
+
+// ErrorStreamUnavailable is returned when the stream did not acknowledge an event, e.g. it is full or unreachable.
+type ErrorStreamUnavailable struct {
+	cause error
+}
+
+func (e *ErrorStreamUnavailable) Error() string {
+	return "events stream is unavailable: " + e.cause.Error()
+}
+
+func (e *ErrorStreamUnavailable) Unwrap() error {
+	return e.cause
+}
+
+func NewErrorStreamUnavailable(cause error) *ErrorStreamUnavailable {
+	return &ErrorStreamUnavailable{cause: cause}
+}
+
+// ErrorQuotaExceeded is returned when the organization ingested every event its monthly quota allows.
+type ErrorQuotaExceeded struct {
+	ResetsAt time.Time
+}
+
+func (e *ErrorQuotaExceeded) Error() string {
+	return "monthly event quota of the organization is exceeded"
+}
+
+func NewErrorQuotaExceeded(resetsAt time.Time) *ErrorQuotaExceeded {
+	return &ErrorQuotaExceeded{ResetsAt: resetsAt}
+}
@@ -1,37 +1,140 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"time"
+	"zori/internal/config"
 	"zori/internal/natsstream"
 	"zori/internal/storage/postgres/models"
 	"zori/services/ingestion/types"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
 )
 
 type Ingestor struct {
 	natsStream *natsstream.Stream
+
+	stallWait  time.Duration
+	ackTimeout time.Duration
 }
 
-func NewIngestor(natsStream *natsstream.Stream) *Ingestor {
+func NewIngestor(natsStream *natsstream.Stream, cfg *config.Config) *Ingestor {
+	err := natsStream.UpsertJetStream(natsstream.RawEventsStream, natsstream.RawEventsSubject)
+	if err != nil {
+		panic(err)
+	}
+
 	return &Ingestor{
 		natsStream: natsStream,
+		stallWait:  cfg.NatsPublishStallWait,
+		ackTimeout: cfg.NatsPublishAckTimeout,
 	}
 }
 
+// Ingest publishes the event to the raw events stream and waits until the stream acknowledges it.
 func (i *Ingestor) Ingest(project *models.Project, clientEvent *types.ClientEventV1) error {
-	eventFrame := types.ClientEventFrameV1{
-		ClientEventV1:  clientEvent,
-		ProjectID:      project.ID,
-		OrganizationID: project.OrganizationID,
+	return i.IngestBatch(project, []*types.ClientEventV1{clientEvent})[0]
+}
+
+// IngestBatch publishes every event of the batch at once and waits for the stream to acknowledge them,
+// errors are positioned the same way as the events so callers can report results per event.
+func (i *Ingestor) IngestBatch(project *models.Project, clientEvents []*types.ClientEventV1) []error {
+	errs := make([]error, len(clientEvents))
+	futures := make([]nats.PubAckFuture, len(clientEvents))
+
+	for idx, clientEvent := range clientEvents {
+		eventFrame := types.ClientEventFrameV1{
+			ClientEventV1:  clientEvent,
+			ProjectID:      project.ID,
+			OrganizationID: project.OrganizationID,
+			BotFilter:      project.BotFilter,
+		}
+
+		eventFrameBytes, err := json.Marshal(&eventFrame)
+		if err != nil {
+			errs[idx] = err
+			continue
+		}
+
+		publishOpts := []nats.PubOpt{nats.StallWait(i.stallWait)}
+		// MsgId lets the stream drop duplicates when the tracker retries an event it already sent.
+		if msgID := deduplicationID(project, clientEvent); msgID != "" {
+			publishOpts = append(publishOpts, nats.MsgId(msgID))
+		}
+
+		futures[idx], err = i.natsStream.GetJetStream().PublishAsync(natsstream.RawEventsSubject, eventFrameBytes, publishOpts...)
+		if err != nil {
+			errs[idx] = publishError(err)
+		}
 	}
 
-	eventFrameBytes, err := json.Marshal(&eventFrame)
+	ackTimeout := time.NewTimer(i.ackTimeout)
+	defer ackTimeout.Stop()
+
+	for idx, future := range futures {
+		if future == nil {
+			continue
+		}
+
+		select {
+		case <-future.Ok():
+		case err := <-future.Err():
+			errs[idx] = publishError(err)
+		case <-ackTimeout.C:
+			// the timer fires only once, events which were not acknowledged by now are reported as failed
+			for pendingIdx := idx; pendingIdx < len(futures); pendingIdx++ {
+				if futures[pendingIdx] != nil && errs[pendingIdx] == nil {
+					errs[pendingIdx] = pollPublishAck(futures[pendingIdx])
+				}
+			}
+			return errs
+		}
+	}
+
+	return errs
+}
+
+// deduplicationID returns the ID the stream deduplicates the event by, scoped to the project since event IDs are
+// chosen by trackers. Events without a UUID are not deduplicated, a tracker repeating some other ID would otherwise
+// have its events dropped while they are reported as accepted.
+func deduplicationID(project *models.Project, clientEvent *types.ClientEventV1) string {
+	eventID, err := uuid.Parse(clientEvent.ClientGeneratedEventID)
 	if err != nil {
-		return err
+		return ""
+	}
+
+	return project.ID + ":" + eventID.String()
+}
+
+// pollPublishAck returns the outcome of the publish without waiting for the ack.
+func pollPublishAck(future nats.PubAckFuture) error {
+	select {
+	case <-future.Ok():
+		return nil
+	case err := <-future.Err():
+		return publishError(err)
+	default:
+		return NewErrorStreamUnavailable(nats.ErrTimeout)
+	}
+}
+
+// Drain waits until every event published so far has been acknowledged by the stream.
+func (i *Ingestor) Drain(ctx context.Context) error {
+	select {
+	case <-i.natsStream.GetJetStream().PublishAsyncComplete():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
-	if err = i.natsStream.GetConnection().Publish("events:raw", eventFrameBytes); err != nil {
-		return err
+func publishError(err error) error {
+	if errors.Is(err, nats.ErrTooManyStalledMsgs) {
+		return NewErrorBackpressure()
 	}
 
-	return nil
+	return NewErrorStreamUnavailable(err)
 }
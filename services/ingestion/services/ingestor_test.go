@@ -0,0 +1,25 @@
+package services
+
+import (
+	"testing"
+	"zori/internal/storage/postgres/models"
+	"zori/services/ingestion/types"
+)
+
+func TestDeduplicationID(t *testing.T) {
+	project := &models.Project{ID: "project"}
+	otherProject := &models.Project{ID: "other-project"}
+	event := &types.ClientEventV1{ClientGeneratedEventID: "123E4567-E89B-12D3-A456-426614174000"}
+
+	msgID := deduplicationID(project, event)
+	if msgID != "project:123e4567-e89b-12d3-a456-426614174000" {
+		t.Errorf("Expected the event ID scoped to the project, got %q", msgID)
+	}
+	if deduplicationID(otherProject, event) == msgID {
+		t.Error("Expected the same event ID of another project not to be a duplicate")
+	}
+
+	if msgID := deduplicationID(project, &types.ClientEventV1{ClientGeneratedEventID: "1"}); msgID != "" {
+		t.Errorf("Expected events without a UUID not to be deduplicated, got %q", msgID)
+	}
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+	"zori/internal/cache"
+	"zori/internal/config"
+	"zori/internal/storage/postgres"
+	"zori/internal/storage/postgres/models"
+	projectsData "zori/services/projects/data"
+	projectsServices "zori/services/projects/services"
+
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+// ProjectLookup resolves projects from publishable tokens through the cache. Invalid tokens are cached as well
+// so repeated requests with a bad token do not reach Postgres, and cached projects are dropped when the project
+// is updated or deleted.
+type ProjectLookup struct {
+	projectService *projectsServices.ProjectService
+	cache          cache.Cache
+	listener       *pgdriver.Listener
+
+	ttl         time.Duration
+	negativeTTL time.Duration
+}
+
+func NewProjectLookup(
+	projectService *projectsServices.ProjectService,
+	projectCache cache.Cache,
+	db *postgres.PostgresDB,
+	cfg *config.Config,
+) *ProjectLookup {
+	return &ProjectLookup{
+		projectService: projectService,
+		cache:          projectCache,
+		listener:       pgdriver.NewListener(db.DB),
+		ttl:            cfg.ProjectCacheTTL,
+		negativeTTL:    cfg.ProjectCacheNegativeTTL,
+	}
+}
+
+// invalidProjectToken is cached for tokens without a project
+var invalidProjectToken = []byte{}
+
+func projectCacheKey(token string) string {
+	return "project_token:" + token
+}
+
+// GetProjectByPublishableToken returns the project of the token, sql.ErrNoRows when there is none
+func (l *ProjectLookup) GetProjectByPublishableToken(ctx context.Context, token string) (*models.Project, error) {
+	key := projectCacheKey(token)
+
+	if cached, err := l.cache.Get(ctx, key); err == nil {
+		if len(cached) == 0 {
+			return nil, sql.ErrNoRows
+		}
+
+		project := &models.Project{}
+		if err := json.Unmarshal(cached, project); err == nil {
+			return project, nil
+		}
+	}
+
+	project, err := l.projectService.GetProjectByPublishableToken(token)
+	if errors.Is(err, sql.ErrNoRows) {
+		l.cache.Set(ctx, key, invalidProjectToken, l.negativeTTL)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	projectBytes, err := json.Marshal(project)
+	if err != nil {
+		return nil, err
+	}
+	l.cache.Set(ctx, key, projectBytes, l.ttl)
+
+	return project, nil
+}
+
+// Listen drops cached projects as they are updated or deleted until the listener is closed. Notifications
+// missed while the connection is down are covered by the ttl of cached projects.
+func (l *ProjectLookup) Listen(ctx context.Context) error {
+	if err := l.listener.Listen(ctx, projectsData.ProjectChangesChannel); err != nil {
+		return err
+	}
+
+	go func() {
+		for notification := range l.listener.CreateChannel() {
+			if err := l.cache.Delete(context.Background(), projectCacheKey(notification.Payload)); err != nil {
+				log.Printf("Error invalidating cached project: %v", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (l *ProjectLookup) Close() error {
+	return l.listener.Close()
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+	"zori/internal/config"
+	"zori/internal/storage/postgres/models"
+	projectsData "zori/services/projects/data"
+)
+
+// UsageTracker counts accepted events against the monthly event quota of organizations. Counts are kept in memory
+// and added to Postgres every flush interval, quotas and the usage recorded by other ingestion servers are read back
+// every refresh interval, so an organization may go over its quota by what is ingested in between.
+type UsageTracker struct {
+	usageData       *projectsData.UsageData
+	flushInterval   time.Duration
+	refreshInterval time.Duration
+	now             func() time.Time
+
+	mu            sync.Mutex
+	organizations map[string]*organizationUsage
+	// pending holds the events recorded since the last flush, flushing the ones being written to Postgres
+	pending  map[usageKey]int64
+	flushing map[usageKey]int64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+type usageKey struct {
+	organizationID string
+	projectID      string
+	month          time.Time
+}
+
+// organizationUsage is the quota and the events of an organization in month as last read from Postgres,
+// plus the events recorded since
+type organizationUsage struct {
+	month       time.Time
+	quota       *int64
+	events      int64
+	refreshedAt time.Time
+}
+
+func NewUsageTracker(usageData *projectsData.UsageData, cfg *config.Config) *UsageTracker {
+	return &UsageTracker{
+		usageData:       usageData,
+		flushInterval:   cfg.UsageFlushInterval,
+		refreshInterval: cfg.UsageRefreshInterval,
+		now:             time.Now,
+		organizations:   make(map[string]*organizationUsage),
+		pending:         make(map[usageKey]int64),
+		flushing:        make(map[usageKey]int64),
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+}
+
+// Check returns ErrorQuotaExceeded once the organization of the project ingested as many events as its quota
+// allows in the current month. When the usage cannot be read the last known usage is used, if there is any.
+func (t *UsageTracker) Check(ctx context.Context, project *models.Project) error {
+	now := t.now()
+	month := models.UsageMonth(now)
+
+	t.mu.Lock()
+	usage, ok := t.organizations[project.OrganizationID]
+	t.mu.Unlock()
+
+	if !ok || !usage.month.Equal(month) || now.Sub(usage.refreshedAt) >= t.refreshInterval {
+		refreshed, err := t.refresh(ctx, project.OrganizationID, month, now)
+		if err != nil {
+			if !ok || !usage.month.Equal(month) {
+				return err
+			}
+			log.Printf("Error refreshing usage of organization %s: %v", project.OrganizationID, err)
+		} else {
+			usage = refreshed
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if usage.quota != nil && usage.events >= *usage.quota {
+		return NewErrorQuotaExceeded(month.AddDate(0, 1, 0))
+	}
+
+	return nil
+}
+
+// refresh reads the quota and usage of the organization, the events recorded but not flushed yet are added on top
+func (t *UsageTracker) refresh(ctx context.Context, orgID string, month time.Time, now time.Time) (*organizationUsage, error) {
+	quota, err := t.usageData.GetMonthlyEventQuota(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := t.usageData.CountOrganizationEvents(ctx, orgID, month)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, unflushed := range []map[usageKey]int64{t.pending, t.flushing} {
+		for key, count := range unflushed {
+			if key.organizationID == orgID && key.month.Equal(month) {
+				events += count
+			}
+		}
+	}
+
+	usage := &organizationUsage{month: month, quota: quota, events: events, refreshedAt: now}
+	t.organizations[orgID] = usage
+
+	return usage, nil
+}
+
+// Record counts events accepted for the project
+func (t *UsageTracker) Record(project *models.Project, events int) {
+	if events <= 0 {
+		return
+	}
+
+	month := models.UsageMonth(t.now())
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pending[usageKey{organizationID: project.OrganizationID, projectID: project.ID, month: month}] += int64(events)
+
+	if usage, ok := t.organizations[project.OrganizationID]; ok && usage.month.Equal(month) {
+		usage.events += int64(events)
+	}
+}
+
+// Flush adds the recorded events to the usage in Postgres, they are kept for the next flush when it fails
+func (t *UsageTracker) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	t.flushing, t.pending = t.pending, make(map[usageKey]int64)
+	flushing := t.flushing
+	t.mu.Unlock()
+
+	if len(flushing) == 0 {
+		return nil
+	}
+
+	now := t.now()
+	usage := make([]*models.OrganizationUsage, 0, len(flushing))
+	for key, events := range flushing {
+		usage = append(usage, &models.OrganizationUsage{
+			OrganizationID: key.organizationID,
+			ProjectID:      key.projectID,
+			Month:          key.month,
+			Events:         events,
+			UpdatedAt:      now,
+		})
+	}
+
+	err := t.usageData.AddUsage(ctx, usage)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err != nil {
+		for key, events := range flushing {
+			t.pending[key] += events
+		}
+	}
+	t.flushing = make(map[usageKey]int64)
+
+	return err
+}
+
+// Start flushes the recorded events every flush interval until Stop is called
+func (t *UsageTracker) Start() {
+	go func() {
+		defer close(t.done)
+
+		ticker := time.NewTicker(t.flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := t.Flush(context.Background()); err != nil {
+					log.Printf("Error flushing usage: %v", err)
+				}
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the periodic flush and flushes the events recorded since
+func (t *UsageTracker) Stop(ctx context.Context) error {
+	close(t.stop)
+
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return t.Flush(ctx)
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+	"zori/services/ingestion/data"
+)
+
+// VisitorIdentifier derives the visitor IDs of cookieless projects. IDs are a hash of the salt of the day, the project,
+// the IP address and the user agent, so a visitor keeps their ID for a day and cannot be recognized afterwards
+// once the salt is deleted. Neither the IP address nor the user agent are stored.
+type VisitorIdentifier struct {
+	saltData *data.SaltData
+
+	mu   sync.Mutex
+	day  time.Time
+	salt []byte
+}
+
+func NewVisitorIdentifier(saltData *data.SaltData) *VisitorIdentifier {
+	return &VisitorIdentifier{saltData: saltData}
+}
+
+// VisitorID returns the visitor ID of the request on the UTC day of now
+func (v *VisitorIdentifier) VisitorID(ctx context.Context, now time.Time, projectID string, ip string, userAgent string) (string, error) {
+	salt, err := v.dailySalt(ctx, now)
+	if err != nil {
+		return "", err
+	}
+
+	return visitorHash(salt, projectID, ip, userAgent), nil
+}
+
+// dailySalt returns the salt of the day of now, rotating the cached salt and deleting older salts when the day changes
+func (v *VisitorIdentifier) dailySalt(ctx context.Context, now time.Time) ([]byte, error) {
+	day := now.UTC().Truncate(24 * time.Hour)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.salt != nil && v.day.Equal(day) {
+		return v.salt, nil
+	}
+
+	salt, err := v.saltData.DailySalt(ctx, day)
+	if err != nil {
+		return nil, err
+	}
+
+	// only the salt of the day is kept, visitor IDs of earlier days cannot be derived again
+	if err := v.saltData.DeleteSaltsBefore(ctx, day); err != nil {
+		log.Printf("Error deleting visitor salts: %v", err)
+	}
+
+	v.day = day
+	v.salt = salt
+
+	return salt, nil
+}
+
+// visitorHash hashes the identifying parts of a request, parts are length prefixed so they cannot run into each other
+func visitorHash(salt []byte, projectID string, ip string, userAgent string) string {
+	hash := sha256.New()
+	hash.Write(salt)
+	for _, part := range []string{projectID, ip, userAgent} {
+		hash.Write([]byte{byte(len(part) >> 8), byte(len(part))})
+		hash.Write([]byte(part))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)[:16])
+}
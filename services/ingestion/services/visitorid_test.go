@@ -0,0 +1,29 @@
+package services
+
+import "testing"
+
+func TestVisitorHash(t *testing.T) {
+	salt := []byte("salt")
+	visitorID := visitorHash(salt, "project", "203.0.113.7", "Mozilla/5.0")
+
+	if len(visitorID) != 32 {
+		t.Fatalf("Expected a 32 characters long visitor ID, got %q", visitorID)
+	}
+
+	if again := visitorHash(salt, "project", "203.0.113.7", "Mozilla/5.0"); again != visitorID {
+		t.Errorf("Expected the same visitor ID for the same request, got %q and %q", visitorID, again)
+	}
+
+	others := map[string]string{
+		"other salt":       visitorHash([]byte("pepper"), "project", "203.0.113.7", "Mozilla/5.0"),
+		"other project":    visitorHash(salt, "other", "203.0.113.7", "Mozilla/5.0"),
+		"other ip":         visitorHash(salt, "project", "203.0.113.8", "Mozilla/5.0"),
+		"other user agent": visitorHash(salt, "project", "203.0.113.7", "curl/8.0"),
+		"shifted parts":    visitorHash(salt, "project203.0.113.7", "", "Mozilla/5.0"),
+	}
+	for name, other := range others {
+		if other == visitorID {
+			t.Errorf("Expected %s to change the visitor ID", name)
+		}
+	}
+}
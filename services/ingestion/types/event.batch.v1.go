@@ -0,0 +1,29 @@
+package types
+
+const (
+	BatchEventStatusAccepted = "accepted"
+	BatchEventStatusRejected = "rejected"
+)
+
+// BatchEventResultV1 reports whether a single event of a batch was accepted for processing.
+type BatchEventResultV1 struct {
+	// Index is the position of the event in the submitted batch.
+	Index                  int    `json:"index"`
+	ClientGeneratedEventID string `json:"client_generated_event_id,omitempty"`
+	Status                 string `json:"status"`
+	Error                  string `json:"error,omitempty"`
+}
+
+// BatchIngestResponseV1 is returned from the batch ingestion endpoint,
+// results are ordered the same way events were submitted so the tracker can retry only the rejected ones.
+type BatchIngestResponseV1 struct {
+	Accepted int                  `json:"accepted"`
+	Rejected int                  `json:"rejected"`
+	Results  []BatchEventResultV1 `json:"results"`
+}
+
+// Reject marks the event as rejected with the reason of the rejection.
+func (r *BatchEventResultV1) Reject(err error) {
+	r.Status = BatchEventStatusRejected
+	r.Error = err.Error()
+}
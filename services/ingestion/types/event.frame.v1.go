@@ -5,16 +5,46 @@ type ClientEventFrameV1 struct {
 	*ClientEventV1
 	ProjectID      string `json:"project_id"`
 	OrganizationID string `json:"organization_id"`
+	// BotFilter is the bot filter of the project when the event was ingested
+	BotFilter string `json:"bot_filter"`
 
 	LocationCountryISO *string `json:"location_country_iso"`
 	LocationCity       *string `json:"location_city"`
+	// LocationRegionISO is the ISO 3166-2 code of the region, e.g. US-CA
+	LocationRegionISO  *string `json:"location_region_iso"`
+	LocationRegion     *string `json:"location_region"`
+	LocationPostalCode *string `json:"location_postal_code"`
+	// LocationLatitude and LocationLongitude are rounded to a tenth of a degree, about 11km
+	LocationLatitude  *float32 `json:"location_latitude"`
+	LocationLongitude *float32 `json:"location_longitude"`
+	LocationTimezone  *string  `json:"location_timezone"`
+	// LocationCityNames and LocationRegionNames hold the names by language code
+	LocationCityNames   map[string]string `json:"location_city_names"`
+	LocationRegionNames map[string]string `json:"location_region_names"`
+
+	// ASN is the autonomous system the IP belongs to, ASOrganization the network operator such as the ISP
+	ASN            *uint32 `json:"asn"`
+	ASOrganization *string `json:"as_organization"`
 
 	BrowserName *string `json:"browser_name"`
 	OsName      *string `json:"os_name"`
 	DeviceType  *string `json:"device_type"`
+	IsBot       bool    `json:"is_bot"`
 
 	ReferredDomain *string `json:"referred_domain"`
 	ReferrerPath   *string `json:"referrer_path"`
 
+	// Channel is the channel the traffic came through, SourceName the search engine, network or site it came from
+	Channel    string `json:"channel"`
+	SourceName string `json:"source_name"`
+
 	PagePath *string `json:"page_path"`
+
+	// CustomPropertiesJSON is the JSON encoded CustomProperties, CustomPropertiesMap holds the same properties
+	// flattened into dot separated keys with string values so they can be indexed and filtered on.
+	CustomPropertiesJSON string            `json:"custom_properties_json"`
+	CustomPropertiesMap  map[string]string `json:"custom_properties_map"`
+
+	// TraitsJSON is the JSON encoded Traits of identify events
+	TraitsJSON string `json:"traits_json"`
 }
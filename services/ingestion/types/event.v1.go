@@ -1,6 +1,37 @@
 package types
 
-import "time"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Limits for custom properties, enforced at ingestion so a single event cannot blow up storage.
+const (
+	// MaxCustomPropertiesKeys is the maximum number of keys, nested keys included.
+	MaxCustomPropertiesKeys = 50
+	// MaxCustomPropertiesDepth is the maximum nesting of objects and arrays, a flat object has a depth of 1.
+	MaxCustomPropertiesDepth = 3
+	// MaxCustomPropertiesSize is the maximum size in bytes of the JSON encoded properties.
+	MaxCustomPropertiesSize    = 8 * 1024
+	MaxCustomPropertyKeyLength = 128
+)
+
+// Event types, events without a type are tracked like track events.
+const (
+	// EventTypeTrack is a page view, click or custom event
+	EventTypeTrack = "track"
+	// EventTypeIdentify links the visitor to a known user and sets the traits of the user
+	EventTypeIdentify = "identify"
+	// EventTypeAlias links another visitor ID of the same user, such as one from a different device, to the user
+	EventTypeAlias = "alias"
+)
+
+// MaxUserIDLength is the maximum length of user IDs and previous visitor IDs of identify and alias events.
+const MaxUserIDLength = 255
 
 // ClientEventV1 represents an event sent from a tracking script to Zori for ingestion.
 type ClientEventV1 struct {
@@ -24,8 +55,165 @@ type ClientEventV1 struct {
 	// ClickOn is used to help us build custom funnels and goals for user conversion.
 	ClickOn *string `json:"click_on"`
 	// ClickPosition represents the position of the click on the element.
-	// ClickPosition is used to track the exact location of the click within the element in order to build heatmaps and analyze user behavior.
-	ClickPosition    *[]float64        `json:"click_position"`
+	// ClickPosition is used to track the exact location of the click within the element in order to analyze user behavior.
+	ClickPosition *[]float64 `json:"click_position"`
+	// PagePosition is the position of the click as [x, y] in CSS pixels from the top left corner of the page.
+	// Heatmaps place clicks by their page position, clicks without one or without a viewport width are left out.
+	PagePosition *[]float64 `json:"page_position"`
+	// ViewportWidth and ViewportHeight are the size of the browser viewport in CSS pixels when the event happened,
+	// heatmaps use them to normalize page positions across screen sizes.
+	ViewportWidth    *int              `json:"viewport_width"`
+	ViewportHeight   *int              `json:"viewport_height"`
 	UTMParameters    map[string]string `json:"utm_parameters"`
 	CustomProperties map[string]any    `json:"custom_properties"`
+	// Type is one of the event types, identify and alias events are stored as identities instead of events.
+	Type string `json:"type"`
+	// UserID is the ID of the user in the product of the customer, required for identify and alias events.
+	UserID string `json:"user_id"`
+	// PreviousID is the visitor ID alias events link to UserID.
+	PreviousID string `json:"previous_id"`
+	// Traits describe the user of identify events, they are limited the same way as custom properties.
+	Traits map[string]any `json:"traits"`
+}
+
+// IsIdentity reports whether the event links a visitor to a user instead of tracking an event.
+func (e *ClientEventV1) IsIdentity() bool {
+	return e.Type == EventTypeIdentify || e.Type == EventTypeAlias
+}
+
+// ValidateBatchEvent checks an event sent in a batch. Batch events are retried one by one, so on top of Validate
+// they need a UUID the stream deduplicates retries with, and the time they happened since they may be sent late.
+func (e *ClientEventV1) ValidateBatchEvent() error {
+	if e.VisitorID == "" {
+		return errors.New("visitor_id is required")
+	}
+
+	if _, err := uuid.Parse(e.ClientGeneratedEventID); err != nil {
+		return errors.New("client_generated_event_id must be a valid UUID")
+	}
+
+	if e.ClientTimeStampUTC.IsZero() {
+		return errors.New("client_timestamp_utc is required")
+	}
+
+	return e.Validate()
+}
+
+// Validate checks the optional fields of the event, it is all single events sent to /ingest are held to.
+func (e *ClientEventV1) Validate() error {
+	if e.ClickPosition != nil && len(*e.ClickPosition) != 0 && len(*e.ClickPosition) != 2 {
+		return errors.New("click_position must contain exactly two coordinates")
+	}
+
+	if e.PagePosition != nil && len(*e.PagePosition) != 0 && len(*e.PagePosition) != 2 {
+		return errors.New("page_position must contain exactly two coordinates")
+	}
+
+	if !validViewportSize(e.ViewportWidth) || !validViewportSize(e.ViewportHeight) {
+		return fmt.Errorf("viewport_width and viewport_height must be between 1 and %d", MaxViewportSize)
+	}
+
+	if err := e.validateIdentity(); err != nil {
+		return err
+	}
+
+	if err := validateProperties("traits", e.Traits); err != nil {
+		return err
+	}
+
+	return validateProperties("custom_properties", e.CustomProperties)
+}
+
+func (e *ClientEventV1) validateIdentity() error {
+	switch e.Type {
+	case "", EventTypeTrack:
+		return nil
+	case EventTypeIdentify, EventTypeAlias:
+	default:
+		return fmt.Errorf("type must be one of %s, %s or %s", EventTypeTrack, EventTypeIdentify, EventTypeAlias)
+	}
+
+	if e.UserID == "" || len(e.UserID) > MaxUserIDLength {
+		return fmt.Errorf("user_id is required for %s events and may be at most %d characters long", e.Type, MaxUserIDLength)
+	}
+
+	if e.Type == EventTypeAlias && (e.PreviousID == "" || len(e.PreviousID) > MaxUserIDLength) {
+		return fmt.Errorf("previous_id is required for alias events and may be at most %d characters long", MaxUserIDLength)
+	}
+
+	return nil
+}
+
+// MaxViewportSize is the largest viewport width or height accepted, in CSS pixels.
+const MaxViewportSize = 16384
+
+func validViewportSize(size *int) bool {
+	return size == nil || (*size > 0 && *size <= MaxViewportSize)
+}
+
+// validateProperties checks custom properties and traits against the custom properties limits, field names the
+// properties in errors
+func validateProperties(field string, properties map[string]any) error {
+	if len(properties) == 0 {
+		return nil
+	}
+
+	keys, err := countPropertiesKeys(field, properties, 1)
+	if err != nil {
+		return err
+	}
+	if keys > MaxCustomPropertiesKeys {
+		return fmt.Errorf("%s may contain at most %d keys", field, MaxCustomPropertiesKeys)
+	}
+
+	propertiesBytes, err := json.Marshal(properties)
+	if err != nil {
+		return fmt.Errorf("%s must be valid JSON", field)
+	}
+	if len(propertiesBytes) > MaxCustomPropertiesSize {
+		return fmt.Errorf("%s may be at most %d bytes", field, MaxCustomPropertiesSize)
+	}
+
+	return nil
+}
+
+// countPropertiesKeys walks the value and counts keys of all nested objects, failing once the depth limit is exceeded
+func countPropertiesKeys(field string, value any, depth int) (int, error) {
+	if depth > MaxCustomPropertiesDepth {
+		return 0, fmt.Errorf("%s may be nested at most %d levels deep", field, MaxCustomPropertiesDepth)
+	}
+
+	keys := 0
+	switch v := value.(type) {
+	case map[string]any:
+		for key, nested := range v {
+			if len(key) > MaxCustomPropertyKeyLength {
+				return 0, fmt.Errorf("%s keys may be at most %d characters long", field, MaxCustomPropertyKeyLength)
+			}
+
+			nestedKeys, err := countNestedProperties(field, nested, depth)
+			if err != nil {
+				return 0, err
+			}
+			keys += 1 + nestedKeys
+		}
+	case []any:
+		for _, nested := range v {
+			nestedKeys, err := countNestedProperties(field, nested, depth)
+			if err != nil {
+				return 0, err
+			}
+			keys += nestedKeys
+		}
+	}
+
+	return keys, nil
+}
+
+func countNestedProperties(field string, value any, depth int) (int, error) {
+	switch value.(type) {
+	case map[string]any, []any:
+		return countPropertiesKeys(field, value, depth+1)
+	}
+	return 0, nil
 }
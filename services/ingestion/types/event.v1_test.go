@@ -0,0 +1,109 @@
+package types
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientEventV1Validate(t *testing.T) {
+	newEvent := func(customProperties map[string]any) *ClientEventV1 {
+		return &ClientEventV1{
+			ClientGeneratedEventID: "123e4567-e89b-12d3-a456-426614174000",
+			VisitorID:              "visitor",
+			ClientTimeStampUTC:     time.Now().UTC(),
+			CustomProperties:       customProperties,
+		}
+	}
+
+	tooManyKeys := map[string]any{}
+	for i := 0; i <= MaxCustomPropertiesKeys; i++ {
+		tooManyKeys[strings.Repeat("k", i+1)] = i
+	}
+
+	withViewport := func(width int, height int) *ClientEventV1 {
+		event := newEvent(nil)
+		event.ViewportWidth = &width
+		event.ViewportHeight = &height
+		return event
+	}
+
+	withPagePosition := func(position ...float64) *ClientEventV1 {
+		event := newEvent(nil)
+		event.PagePosition = &position
+		return event
+	}
+
+	withIdentity := func(eventType string, userID string, previousID string) *ClientEventV1 {
+		event := newEvent(nil)
+		event.Type = eventType
+		event.UserID = userID
+		event.PreviousID = previousID
+		return event
+	}
+
+	tests := []struct {
+		name  string
+		event *ClientEventV1
+		valid bool
+	}{
+		{"valid event", newEvent(nil), true},
+		{"legacy event without id and timestamp", &ClientEventV1{ClientGeneratedEventID: "not-a-uuid", VisitorID: "visitor"}, true},
+		{"nested custom properties", newEvent(map[string]any{"plan": "pro", "cart": map[string]any{"items": []any{1.0, 2.0}}}), true},
+		{"too deep custom properties", newEvent(map[string]any{"a": map[string]any{"b": map[string]any{"c": map[string]any{"d": 1.0}}}}), false},
+		{"too many custom properties keys", newEvent(tooManyKeys), false},
+		{"too long custom property key", newEvent(map[string]any{strings.Repeat("k", MaxCustomPropertyKeyLength+1): 1.0}), false},
+		{"too large custom properties", newEvent(map[string]any{"blob": strings.Repeat("x", MaxCustomPropertiesSize)}), false},
+		{"viewport", withViewport(1440, 900), true},
+		{"empty viewport", withViewport(0, 900), false},
+		{"too large viewport", withViewport(MaxViewportSize+1, 900), false},
+		{"page position", withPagePosition(120, 2400), true},
+		{"page position with one coordinate", withPagePosition(120), false},
+		{"track event", withIdentity(EventTypeTrack, "", ""), true},
+		{"unknown type", withIdentity("page", "", ""), false},
+		{"identify", withIdentity(EventTypeIdentify, "user-42", ""), true},
+		{"identify without user id", withIdentity(EventTypeIdentify, "", ""), false},
+		{"too long user id", withIdentity(EventTypeIdentify, strings.Repeat("u", MaxUserIDLength+1), ""), false},
+		{"alias", withIdentity(EventTypeAlias, "user-42", "other-visitor"), true},
+		{"alias without previous id", withIdentity(EventTypeAlias, "user-42", ""), false},
+		{"too deep traits", &ClientEventV1{ClientGeneratedEventID: "123e4567-e89b-12d3-a456-426614174000", VisitorID: "visitor", ClientTimeStampUTC: time.Now(), Type: EventTypeIdentify, UserID: "user-42", Traits: map[string]any{"a": map[string]any{"b": map[string]any{"c": map[string]any{"d": 1.0}}}}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.event.Validate()
+			if test.valid && err != nil {
+				t.Errorf("Expected event to be valid, got error: %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Error("Expected event to be invalid, got no error")
+			}
+		})
+	}
+}
+
+func TestClientEventV1ValidateBatchEvent(t *testing.T) {
+	tests := []struct {
+		name  string
+		event *ClientEventV1
+		valid bool
+	}{
+		{"valid event", &ClientEventV1{ClientGeneratedEventID: "123e4567-e89b-12d3-a456-426614174000", VisitorID: "visitor", ClientTimeStampUTC: time.Now()}, true},
+		{"missing visitor id", &ClientEventV1{ClientGeneratedEventID: "123e4567-e89b-12d3-a456-426614174000", ClientTimeStampUTC: time.Now()}, false},
+		{"invalid event id", &ClientEventV1{ClientGeneratedEventID: "not-a-uuid", VisitorID: "visitor", ClientTimeStampUTC: time.Now()}, false},
+		{"missing timestamp", &ClientEventV1{ClientGeneratedEventID: "123e4567-e89b-12d3-a456-426614174000", VisitorID: "visitor"}, false},
+		{"invalid optional fields", &ClientEventV1{ClientGeneratedEventID: "123e4567-e89b-12d3-a456-426614174000", VisitorID: "visitor", ClientTimeStampUTC: time.Now(), Type: "page"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.event.ValidateBatchEvent()
+			if test.valid && err != nil {
+				t.Errorf("Expected event to be valid, got error: %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Error("Expected event to be invalid, got no error")
+			}
+		})
+	}
+}
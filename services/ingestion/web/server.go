@@ -1,41 +1,93 @@
 package web
 
 import (
+	"bufio"
+	"bytes"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
+	"log"
+	"math"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+	"zori/internal/clientip"
+	"zori/internal/config"
+	"zori/internal/origins"
+	"zori/internal/ratelimit"
+	"zori/internal/storage/postgres/models"
 	"zori/services/ingestion/services"
 	"zori/services/ingestion/types"
-	projectsServices "zori/services/projects/services"
 
 	"github.com/valyala/fasthttp"
 )
 
+// maxBatchSize caps how many events a single batch request may carry.
+const maxBatchSize = 100
+
 type IngestionServer struct {
-	ingestor       *services.Ingestor
-	projectService *projectsServices.ProjectService
+	ingestor          *services.Ingestor
+	projectLookup     *services.ProjectLookup
+	visitorIdentifier *services.VisitorIdentifier
+	usageTracker      *services.UsageTracker
+
+	projectLimiter *ratelimit.Limiter
+	ipLimiter      *ratelimit.Limiter
+
+	clientIPResolver *clientip.Resolver
+	// untrustedProxyWarning is logged for the first request carrying proxy headers from a peer which is not trusted
+	untrustedProxyWarning sync.Once
+	cookieDomain          string
 }
 
-func NewIngestionServer(ingestor *services.Ingestor, projectService *projectsServices.ProjectService) *IngestionServer {
+func NewIngestionServer(
+	ingestor *services.Ingestor,
+	projectLookup *services.ProjectLookup,
+	visitorIdentifier *services.VisitorIdentifier,
+	usageTracker *services.UsageTracker,
+	cfg *config.Config,
+) *IngestionServer {
+	clientIPResolver, err := clientip.New(cfg.TrustedProxies)
+	if err != nil {
+		panic(err)
+	}
+	if !clientIPResolver.HasTrustedProxies() {
+		log.Printf("Warning: TRUSTED_PROXIES is empty, proxy headers are ignored and behind a reverse proxy " +
+			"every client is seen as the proxy. Set it to the ranges of the proxies in front of ingestion.")
+	}
+
 	return &IngestionServer{
-		ingestor:       ingestor,
-		projectService: projectService,
+		ingestor:          ingestor,
+		projectLookup:     projectLookup,
+		visitorIdentifier: visitorIdentifier,
+		usageTracker:      usageTracker,
+		projectLimiter:    ratelimit.New(cfg.IngestProjectRate, cfg.IngestProjectBurst),
+		ipLimiter:         ratelimit.New(cfg.IngestIPRate, cfg.IngestIPBurst),
+		clientIPResolver:  clientIPResolver,
+		cookieDomain:      cfg.VisitorCookieDomain,
 	}
 }
 
 func (h *IngestionServer) Injest(ctx *fasthttp.RequestCtx) {
 	ctx.Response.Header.Set("Access-Control-Allow-Credentials", "true")
-	ctx.Response.Header.SetBytesV("Access-Control-Allow-Origin", []byte("*"))
 	ctx.Response.Header.SetBytesV("Access-Control-Allow-Methods", []byte("POST"))
 	ctx.Response.Header.SetBytesV("Access-Control-Allow-Headers", []byte("Content-Type, X-Zori-PT, x-zori-version"))
 	ctx.Response.Header.SetBytesV("Access-Control-Max-Age", []byte("86400"))
+	ctx.Response.Header.Set(fasthttp.HeaderVary, fasthttp.HeaderOrigin)
 
-	if string(ctx.Path()) != "/ingest" {
+	path := string(ctx.Path())
+	if path != "/ingest" && path != "/ingest/batch" {
 		ctx.Error("Not Found", fasthttp.StatusNotFound)
 		return
 	}
 
 	if ctx.IsOptions() {
+		// preflight requests carry no project token, the origin is checked against the project once events are posted
+		if origin := ctx.Request.Header.Peek(fasthttp.HeaderOrigin); len(origin) > 0 {
+			ctx.Response.Header.SetBytesV("Access-Control-Allow-Origin", origin)
+		}
 		ctx.Response.SetStatusCode(fasthttp.StatusNoContent)
 		return
 	}
@@ -45,78 +97,429 @@ func (h *IngestionServer) Injest(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	if path == "/ingest/batch" {
+		h.injestBatch(ctx)
+		return
+	}
+
 	var clientEvent types.ClientEventV1
 	if err := json.Unmarshal(ctx.PostBody(), &clientEvent); err != nil {
 		ctx.Error("Failed to decode event payload", fasthttp.StatusBadRequest)
 		return
 	}
 
+	if !h.limitIP(ctx, 1) {
+		return
+	}
+
+	project, ok := h.authorizeProject(ctx)
+	if !ok {
+		return
+	}
+
+	if !h.limitProject(ctx, project, 1) {
+		return
+	}
+
+	visitorID, ok := h.requestVisitorID(ctx, project, clientEvent.VisitorID)
+	if !ok {
+		return
+	}
+
+	if project.Cookieless {
+		clientEvent.VisitorID = visitorID
+	}
+
+	if clientEvent.VisitorID != visitorID {
+		ctx.Error("Missing or Invalid Visitor ID", fasthttp.StatusBadRequest)
+		return
+	}
+
+	if err := clientEvent.Validate(); err != nil {
+		ctx.Error(err.Error(), fasthttp.StatusBadRequest)
+		return
+	}
+
+	// trackers sending single events may leave the timestamp out, the event is then stored at the time it arrived
+	if clientEvent.ClientTimeStampUTC.IsZero() {
+		clientEvent.ClientTimeStampUTC = time.Now().UTC()
+	}
+
+	h.fillRequestMetadata(ctx, &clientEvent)
+
+	if !h.checkQuota(ctx, project) {
+		return
+	}
+
+	if err := h.ingestor.Ingest(project, &clientEvent); err != nil {
+		log.Printf("Error ingesting event: %v", err)
+		statusCode := publishErrorStatusCode(err)
+		setRetryAfter(ctx, statusCode)
+		ctx.Error(fasthttp.StatusMessage(statusCode), statusCode)
+		return
+	}
+	h.usageTracker.Record(project, 1)
+
+	fmt.Fprintf(ctx, "ACCEPTED %d", len(ctx.PostBody()))
+}
+
+// injestBatch accepts a JSON array or NDJSON body of events, every event is validated on its own
+// and the response reports which of them were accepted. A batch carries the events of a single visitor:
+// the visitor of the first event is the one the cookie is checked or issued for, and events of any other
+// visitor are rejected.
+func (h *IngestionServer) injestBatch(ctx *fasthttp.RequestCtx) {
+	rawEvents, err := splitBatchPayload(ctx.PostBody())
+	if err != nil {
+		ctx.Error("Failed to decode batch payload", fasthttp.StatusBadRequest)
+		return
+	}
+
+	if len(rawEvents) == 0 {
+		ctx.Error("Batch contains no events", fasthttp.StatusBadRequest)
+		return
+	}
+
+	if len(rawEvents) > maxBatchSize {
+		ctx.Error(fmt.Sprintf("Batch may contain at most %d events", maxBatchSize), fasthttp.StatusRequestEntityTooLarge)
+		return
+	}
+
+	clientEvents := make([]*types.ClientEventV1, len(rawEvents))
+	decodeErrs := make([]error, len(rawEvents))
+	for idx, rawEvent := range rawEvents {
+		var clientEvent types.ClientEventV1
+		if err := json.Unmarshal(rawEvent, &clientEvent); err != nil {
+			decodeErrs[idx] = errors.New("failed to decode event payload")
+			continue
+		}
+		clientEvents[idx] = &clientEvent
+	}
+
+	firstVisitorID := ""
+	for _, clientEvent := range clientEvents {
+		if clientEvent != nil {
+			firstVisitorID = clientEvent.VisitorID
+			break
+		}
+	}
+
+	if !h.limitIP(ctx, len(rawEvents)) {
+		return
+	}
+
+	project, ok := h.authorizeProject(ctx)
+	if !ok {
+		return
+	}
+
+	if !h.limitProject(ctx, project, len(rawEvents)) {
+		return
+	}
+
+	if !h.checkQuota(ctx, project) {
+		return
+	}
+
+	// the cookie is issued for the visitor of the first event, a batch without one would issue an empty cookie
+	if firstVisitorID == "" && !project.Cookieless {
+		ctx.Error("The first event of the batch must carry a visitor_id", fasthttp.StatusBadRequest)
+		return
+	}
+
+	visitorID, ok := h.requestVisitorID(ctx, project, firstVisitorID)
+	if !ok {
+		return
+	}
+
+	response := types.BatchIngestResponseV1{
+		Results: make([]types.BatchEventResultV1, len(rawEvents)),
+	}
+
+	acceptedEvents := make([]*types.ClientEventV1, 0, len(clientEvents))
+	acceptedIndexes := make([]int, 0, len(clientEvents))
+	for idx, clientEvent := range clientEvents {
+		response.Results[idx].Index = idx
+
+		if clientEvent == nil {
+			response.Results[idx].Reject(decodeErrs[idx])
+			continue
+		}
+
+		response.Results[idx].ClientGeneratedEventID = clientEvent.ClientGeneratedEventID
+
+		if project.Cookieless {
+			clientEvent.VisitorID = visitorID
+		}
+
+		if clientEvent.VisitorID != visitorID {
+			response.Results[idx].Reject(errors.New("visitor_id does not match the visitor of the batch, a batch carries the events of a single visitor"))
+			continue
+		}
+
+		if err := clientEvent.ValidateBatchEvent(); err != nil {
+			response.Results[idx].Reject(err)
+			continue
+		}
+
+		h.fillRequestMetadata(ctx, clientEvent)
+		acceptedEvents = append(acceptedEvents, clientEvent)
+		acceptedIndexes = append(acceptedIndexes, idx)
+	}
+
+	// when the stream could not take some of the events the whole response carries the 429/503 status,
+	// results still tell the tracker which events have to be retried
+	statusCode := fasthttp.StatusOK
+	publishErrs := h.ingestor.IngestBatch(project, acceptedEvents)
+	for i, idx := range acceptedIndexes {
+		if publishErrs[i] != nil {
+			log.Printf("Error ingesting event: %v", publishErrs[i])
+			response.Results[idx].Reject(errors.New("failed to publish event"))
+			statusCode = max(statusCode, publishErrorStatusCode(publishErrs[i]))
+			continue
+		}
+		response.Results[idx].Status = types.BatchEventStatusAccepted
+	}
+
+	for _, result := range response.Results {
+		if result.Status == types.BatchEventStatusAccepted {
+			response.Accepted++
+		} else {
+			response.Rejected++
+		}
+	}
+	h.usageTracker.Record(project, response.Accepted)
+
+	responseBytes, err := json.Marshal(&response)
+	if err != nil {
+		ctx.Error("Failed to encode response", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	setRetryAfter(ctx, statusCode)
+	ctx.SetStatusCode(statusCode)
+	ctx.SetContentType("application/json")
+	ctx.SetBody(responseBytes)
+}
+
+// requestVisitorID returns the visitor ID the events of the request have to carry. Cookieless projects derive it
+// from the request without setting any cookie, other projects read it from the visitor_id cookie.
+// The error response is already written when it returns false.
+func (h *IngestionServer) requestVisitorID(ctx *fasthttp.RequestCtx, project *models.Project, clientVisitorID string) (string, bool) {
+	if !project.Cookieless {
+		return h.visitorIDCookie(ctx, clientVisitorID), true
+	}
+
+	visitorID, err := h.visitorIdentifier.VisitorID(ctx, time.Now(), project.ID, h.clientIP(ctx), string(ctx.UserAgent()))
+	if err != nil {
+		log.Printf("Error deriving visitor ID: %v", err)
+		ctx.Error(fasthttp.StatusMessage(fasthttp.StatusServiceUnavailable), fasthttp.StatusServiceUnavailable)
+		return "", false
+	}
+
+	return visitorID, true
+}
+
+// visitorIDCookie returns the visitor id stored in cookies, if the cookie is not present
+// we assume this is the first time the user is visiting the site and issue one with the given visitor id.
+func (h *IngestionServer) visitorIDCookie(ctx *fasthttp.RequestCtx, visitorID string) string {
 	visitorIDCookieBytes := ctx.Request.Header.Cookie("visitor_id")
 	if visitorIDCookieBytes == nil {
-		// if visitor id is not present in cookies, we assume this is the first time the user is visiting the site
 		firstTimeVisitorCookie := fasthttp.Cookie{}
 		firstTimeVisitorCookie.SetKey("visitor_id")
-		firstTimeVisitorCookie.SetValue(clientEvent.VisitorID)
+		firstTimeVisitorCookie.SetValue(visitorID)
 		firstTimeVisitorCookie.SetMaxAge(3600000)
-		firstTimeVisitorCookie.SetDomain(".zorihq.com")
+		if h.cookieDomain != "" {
+			firstTimeVisitorCookie.SetDomain(h.cookieDomain)
+		}
 		firstTimeVisitorCookie.SetPath(("/"))
 		firstTimeVisitorCookie.SetSecure(false)
 		ctx.Response.Header.SetCookie(&firstTimeVisitorCookie)
 		visitorIDCookieBytes = firstTimeVisitorCookie.Value()
 	}
 
+	return string(visitorIDCookieBytes)
+}
+
+// authorizeProject resolves the project from the publishable token header and checks the request origin against it,
+// the error response is already written when it returns false.
+func (h *IngestionServer) authorizeProject(ctx *fasthttp.RequestCtx) (*models.Project, bool) {
 	projectTokenBytes := ctx.Request.Header.Peek("x-zori-pt")
 	if projectTokenBytes == nil {
 		ctx.Error("X-Zori-PT Missing in the request header", fasthttp.StatusUnauthorized)
-		return
+		return nil, false
 	}
 
 	projectToken := string(projectTokenBytes)
 
-	project, err := h.projectService.GetProjectByPublishableToken(projectToken)
-	if err != nil {
+	project, err := h.projectLookup.GetProjectByPublishableToken(ctx, projectToken)
+	if errors.Is(err, sql.ErrNoRows) {
 		ctx.Error("Invalid Project Token", fasthttp.StatusUnauthorized)
-		return
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error getting project: %v", err)
+		ctx.Error(fasthttp.StatusMessage(fasthttp.StatusServiceUnavailable), fasthttp.StatusServiceUnavailable)
+		return nil, false
 	}
 
-	fmt.Println("Host of the request origin", string(ctx.Request.Host()))
+	if !h.authorizeOrigin(ctx, project) {
+		return nil, false
+	}
 
-	// checking for localhost events
-	requestHost := string(ctx.Request.Host())
-	if strings.Contains(requestHost, "localhost") && project.AllowLocalHost {
-		localhostParts := strings.Split(requestHost, ":")
-		if len(localhostParts) == 2 {
-			host := localhostParts[1]
-			if host != "localhost" {
-				ctx.Error("Invalid Host", fasthttp.StatusBadRequest)
-				return
-			}
+	return project, true
+}
+
+// authorizeOrigin checks the Origin header, or the Referer header when there is none, against the origins of the project
+// and allows the origin in the CORS headers. Localhost is accepted only when the project allows it.
+// The error response is already written when it returns false.
+func (h *IngestionServer) authorizeOrigin(ctx *fasthttp.RequestCtx, project *models.Project) bool {
+	origin := ctx.Request.Header.Peek(fasthttp.HeaderOrigin)
+	source := origin
+	if len(source) == 0 {
+		source = ctx.Request.Header.Referer()
+	}
+
+	host, port, ok := origins.Parse(string(source))
+	if !ok {
+		ctx.Error("Missing or Invalid Origin", fasthttp.StatusForbidden)
+		return false
+	}
+
+	if origins.IsLocalhost(host) {
+		if !project.AllowLocalHost {
+			ctx.Error("Localhost events are not allowed for the project", fasthttp.StatusForbidden)
+			return false
 		}
-	} else if strings.Contains(requestHost, "localhost") && !project.AllowLocalHost {
-		ctx.Error("Localhost events are now allowed for the project", fasthttp.StatusBadRequest)
-		return
+	} else if !origins.Match(project.OriginPatterns(), host, port) {
+		ctx.Error("Origin is not allowed for the project", fasthttp.StatusForbidden)
+		return false
 	}
 
-	if clientEvent.VisitorID != string(visitorIDCookieBytes) {
-		ctx.Error("Missing or Invalid Visitor ID", fasthttp.StatusBadRequest)
-		return
+	if len(origin) > 0 {
+		ctx.Response.Header.SetBytesV("Access-Control-Allow-Origin", origin)
+	}
+
+	return true
+}
+
+// limitIP takes the events of the request from the rate limit of the client IP, proxy headers only name the client
+// of requests from trusted proxies so clients cannot pick the key they are limited by. Behind a proxy which is not
+// trusted all clients share the limit of the proxy, clientIP warns about it.
+// The 429 response is already written when it returns false.
+func (h *IngestionServer) limitIP(ctx *fasthttp.RequestCtx, events int) bool {
+	allowed, retryAfter := h.ipLimiter.Allow(h.clientIP(ctx), events)
+	if !allowed {
+		rejectTooManyRequests(ctx, "Rate limit exceeded for the client", retryAfter)
 	}
+	return allowed
+}
+
+// limitProject takes the events of the request from the rate limit of the project token,
+// the 429 response is already written when it returns false.
+func (h *IngestionServer) limitProject(ctx *fasthttp.RequestCtx, project *models.Project, events int) bool {
+	allowed, retryAfter := h.projectLimiter.Allow(project.ProjectToken, events)
+	if !allowed {
+		rejectTooManyRequests(ctx, "Rate limit exceeded for the project", retryAfter)
+	}
+	return allowed
+}
+
+// checkQuota rejects the request once the organization of the project used its monthly event quota,
+// the error response is already written when it returns false.
+func (h *IngestionServer) checkQuota(ctx *fasthttp.RequestCtx, project *models.Project) bool {
+	err := h.usageTracker.Check(ctx, project)
+	if err == nil {
+		return true
+	}
+
+	var quotaErr *services.ErrorQuotaExceeded
+	if errors.As(err, &quotaErr) {
+		rejectTooManyRequests(ctx, "Monthly event quota exceeded", time.Until(quotaErr.ResetsAt))
+		return false
+	}
+
+	log.Printf("Error checking usage: %v", err)
+	setRetryAfter(ctx, fasthttp.StatusServiceUnavailable)
+	ctx.Error(fasthttp.StatusMessage(fasthttp.StatusServiceUnavailable), fasthttp.StatusServiceUnavailable)
+	return false
+}
 
+// rejectTooManyRequests writes a 429 response telling the client to retry after the given duration in whole seconds.
+func rejectTooManyRequests(ctx *fasthttp.RequestCtx, message string, retryAfter time.Duration) {
+	seconds := max(int(math.Ceil(retryAfter.Seconds())), 1)
+	ctx.Response.Header.Set(fasthttp.HeaderRetryAfter, strconv.Itoa(seconds))
+	ctx.Error(message, fasthttp.StatusTooManyRequests)
+}
+
+// fillRequestMetadata overrides the user agent and IP of the event with the ones of the request.
+func (h *IngestionServer) fillRequestMetadata(ctx *fasthttp.RequestCtx, clientEvent *types.ClientEventV1) {
 	clientEvent.UserAgent = string(ctx.UserAgent())
+	clientEvent.IP = h.clientIP(ctx)
+}
+
+// clientIP returns the single normalized IP address of the visitor, headers set by proxies are only read when the
+// request comes from a trusted proxy. Proxy headers from other peers are logged once since they usually mean a proxy
+// is missing from the trusted ones, which leaves every client behind it with the IP of the proxy.
+func (h *IngestionServer) clientIP(ctx *fasthttp.RequestCtx) string {
+	remoteAddr, ok := netip.AddrFromSlice(ctx.RemoteIP())
+	if !ok {
+		return ctx.RemoteIP().String()
+	}
 
-	// trying to extract user IP
-	cloudFlareHeaderIP := ctx.Request.Header.Peek("cf-connecting-ip")
-	if cloudFlareHeaderIP != nil {
-		clientEvent.IP = string(cloudFlareHeaderIP)
-	} else if xForwardedForHeader := ctx.Request.Header.Peek(fasthttp.HeaderXForwardedFor); xForwardedForHeader != nil {
-		clientEvent.IP = string(xForwardedForHeader)
-	} else {
-		clientEvent.IP = ctx.RemoteIP().String()
+	cloudflareIP := string(ctx.Request.Header.Peek("cf-connecting-ip"))
+	forwardedFor := string(ctx.Request.Header.Peek(fasthttp.HeaderXForwardedFor))
+	if (cloudflareIP != "" || forwardedFor != "") && !h.clientIPResolver.Trusted(remoteAddr) {
+		h.untrustedProxyWarning.Do(func() {
+			log.Printf("Warning: ignoring proxy headers of a request from %s which is not in TRUSTED_PROXIES, "+
+				"add the proxy when ingestion runs behind one", remoteAddr.Unmap())
+		})
 	}
 
-	fmt.Println("Ingested....")
+	return h.clientIPResolver.ClientIP(remoteAddr, cloudflareIP, forwardedFor).String()
+}
 
-	go h.ingestor.Ingest(project, &clientEvent)
+// publishErrorStatusCode maps ingestor errors to 429 when ingestion is applying backpressure
+// and to 503 when the stream cannot accept events.
+func publishErrorStatusCode(err error) int {
+	var backpressureErr *services.ErrorBackpressure
+	if errors.As(err, &backpressureErr) {
+		return fasthttp.StatusTooManyRequests
+	}
 
-	fmt.Fprintf(ctx, "ACCEPTED %d", len(ctx.PostBody()))
+	return fasthttp.StatusServiceUnavailable
+}
+
+func setRetryAfter(ctx *fasthttp.RequestCtx, statusCode int) {
+	switch statusCode {
+	case fasthttp.StatusTooManyRequests:
+		ctx.Response.Header.Set(fasthttp.HeaderRetryAfter, "1")
+	case fasthttp.StatusServiceUnavailable:
+		ctx.Response.Header.Set(fasthttp.HeaderRetryAfter, "5")
+	}
+}
+
+// splitBatchPayload splits the body into raw events, the body is either a JSON array or newline delimited JSON.
+func splitBatchPayload(body []byte) ([]json.RawMessage, error) {
+	body = bytes.TrimSpace(body)
+
+	if bytes.HasPrefix(body, []byte("[")) {
+		var rawEvents []json.RawMessage
+		if err := json.Unmarshal(body, &rawEvents); err != nil {
+			return nil, err
+		}
+		return rawEvents, nil
+	}
+
+	var rawEvents []json.RawMessage
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(body)+1)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		rawEvents = append(rawEvents, json.RawMessage(bytes.Clone(line)))
+	}
+
+	return rawEvents, scanner.Err()
 }
@@ -0,0 +1,40 @@
+package web
+
+import (
+	"testing"
+)
+
+func TestSplitBatchPayload(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		expected  int
+		expectErr bool
+	}{
+		{"json array", `[{"visitor_id":"a"},{"visitor_id":"b"}]`, 2, false},
+		{"empty json array", `[]`, 0, false},
+		{"ndjson", "{\"visitor_id\":\"a\"}\n{\"visitor_id\":\"b\"}\n{\"visitor_id\":\"c\"}\n", 3, false},
+		{"ndjson with blank lines", "\n{\"visitor_id\":\"a\"}\n\n{\"visitor_id\":\"b\"}", 2, false},
+		{"malformed json array", `[{"visitor_id":"a"},`, 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rawEvents, err := splitBatchPayload([]byte(test.body))
+			if test.expectErr {
+				if err == nil {
+					t.Fatal("Expected error, got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if len(rawEvents) != test.expected {
+				t.Fatalf("Expected %d events, got %d", test.expected, len(rawEvents))
+			}
+		})
+	}
+}
@@ -12,7 +12,11 @@ func BuildProjectsDIContainer() fx.Option {
 	return fx.Module("projects",
 		fx.Provide(
 			data.NewProjectData,
+			data.NewGoalData,
+			data.NewUsageData,
 			services.NewProjectService,
+			services.NewGoalService,
+			services.NewUsageService,
 		),
 	)
 }
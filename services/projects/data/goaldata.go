@@ -0,0 +1,103 @@
+package data
+
+import (
+	"context"
+	"zori/internal/storage/postgres"
+	"zori/internal/storage/postgres/models"
+	"zori/services/projects/types"
+
+	"github.com/uptrace/bun"
+)
+
+// GoalData stores the goals of projects, callers make sure the project belongs to the organization
+type GoalData struct {
+	db *bun.DB
+}
+
+func NewGoalData(db *postgres.PostgresDB) *GoalData {
+	return &GoalData{db: db.DB}
+}
+
+func (g *GoalData) ListProjectGoals(ctx context.Context, projectID string) ([]*models.Goal, error) {
+	goals := []*models.Goal{}
+	err := g.db.NewSelect().
+		Model(&goals).
+		Where("project_id = ?", projectID).
+		Order("created_at").
+		Scan(ctx)
+	return goals, err
+}
+
+func (g *GoalData) GetGoal(ctx context.Context, projectID string, goalID string) (*models.Goal, error) {
+	goal := &models.Goal{}
+	err := g.db.NewSelect().
+		Model(goal).
+		Where("id = ?", goalID).
+		Where("project_id = ?", projectID).
+		Scan(ctx)
+	return goal, err
+}
+
+func (g *GoalData) CreateGoal(ctx context.Context, projectID string, req *types.GoalRequest) (*models.Goal, error) {
+	goal := &models.Goal{
+		ProjectID: projectID,
+		Name:      req.Name,
+		Type:      req.Type,
+		Match:     req.Match,
+		Value:     req.Value,
+	}
+
+	_, err := g.db.NewInsert().
+		Model(goal).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return goal, nil
+}
+
+func (g *GoalData) UpdateGoal(ctx context.Context, projectID string, goalID string, req *types.GoalRequest) (*models.Goal, error) {
+	goal := &models.Goal{}
+
+	_, err := g.db.NewUpdate().
+		Model(goal).
+		Set("name = ?", req.Name).
+		Set("type = ?", req.Type).
+		Set("match = ?", req.Match).
+		Set("value = ?", req.Value).
+		Where("id = ?", goalID).
+		Where("project_id = ?", projectID).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return goal, nil
+}
+
+func (g *GoalData) DeleteGoal(ctx context.Context, projectID string, goalID string) error {
+	_, err := g.db.NewDelete().
+		Model(&models.Goal{}).
+		Where("id = ?", goalID).
+		Where("project_id = ?", projectID).
+		Exec(ctx)
+	return err
+}
+
+func (g *GoalData) GoalExists(ctx context.Context, projectID string, goalID string) (bool, error) {
+	return g.db.NewSelect().
+		Model(&models.Goal{}).
+		Where("id = ?", goalID).
+		Where("project_id = ?", projectID).
+		Exists(ctx)
+}
+
+func (g *GoalData) CountProjectGoals(ctx context.Context, projectID string) (int, error) {
+	return g.db.NewSelect().
+		Model(&models.Goal{}).
+		Where("project_id = ?", projectID).
+		Count(ctx)
+}
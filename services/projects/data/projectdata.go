@@ -2,6 +2,7 @@ package data
 
 import (
 	"context"
+	"log"
 	"time"
 	"zori/internal/ctx"
 	"zori/internal/storage/postgres"
@@ -10,8 +11,14 @@ import (
 	"zori/services/projects/types"
 
 	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"github.com/uptrace/bun/driver/pgdriver"
 )
 
+// ProjectChangesChannel is the Postgres channel notified with the token of every updated or deleted project,
+// services caching projects by token listen on it to drop stale projects
+const ProjectChangesChannel = "project_changes"
+
 type ProjectData struct {
 	db *bun.DB
 }
@@ -55,12 +62,21 @@ func (p *ProjectData) CreateProject(c *ctx.Ctx, req *types.CreateProjectRequest)
 		return nil, err
 	}
 
+	sessionTimeoutMinutes := req.SessionTimeoutMinutes
+	if sessionTimeoutMinutes == 0 {
+		sessionTimeoutMinutes = models.DefaultSessionTimeoutMinutes
+	}
+
 	project := &models.Project{
-		Name:           req.Name,
-		Domain:         req.WebsiteURL,
-		ProjectToken:   projectToken,
-		OrganizationID: c.OrgID(),
-		AllowLocalHost: req.AllowLocalHost,
+		Name:                  req.Name,
+		Domain:                req.WebsiteURL,
+		ProjectToken:          projectToken,
+		OrganizationID:        c.OrgID(),
+		AllowLocalHost:        req.AllowLocalHost,
+		SessionTimeoutMinutes: sessionTimeoutMinutes,
+		Cookieless:            req.Cookieless,
+		AllowedOrigins:        req.AllowedOrigins,
+		BotFilter:             req.BotFilter,
 	}
 
 	_, err = p.db.NewInsert().
@@ -91,22 +107,56 @@ func (p *ProjectData) UpdateProject(ctx context.Context, projectID string, orgID
 		query = query.Set("domain = ?", req.WebsiteURL)
 	}
 	query = query.Set("allow_local_host = ?", req.AllowLocalHost)
+	if req.SessionTimeoutMinutes != nil {
+		query = query.Set("session_timeout_minutes = ?", *req.SessionTimeoutMinutes)
+	}
+	if req.Cookieless != nil {
+		query = query.Set("cookieless = ?", *req.Cookieless)
+	}
+	if req.AllowedOrigins != nil {
+		query = query.Set("allowed_origins = ?", pgdialect.Array(*req.AllowedOrigins))
+	}
+	if req.BotFilter != "" {
+		query = query.Set("bot_filter = ?", req.BotFilter)
+	}
 
 	_, err := query.Exec(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	p.notifyProjectChanged(ctx, project.ProjectToken)
+
 	return project, nil
 }
 
 func (p *ProjectData) DeleteProject(c *ctx.Ctx, projectID string) error {
+	project := &models.Project{}
 	_, err := p.db.NewDelete().
-		Model(&models.Project{}).
+		Model(project).
 		Where("id = ?", projectID).
 		Where("organization_id = ?", c.OrgID()).
+		Returning("project_token").
 		Exec(context.Background())
-	return err
+	if err != nil {
+		return err
+	}
+
+	p.notifyProjectChanged(context.Background(), project.ProjectToken)
+
+	return nil
+}
+
+// notifyProjectChanged notifies listeners of ProjectChangesChannel, a failed notification is only logged
+// since caches expire their projects anyway
+func (p *ProjectData) notifyProjectChanged(ctx context.Context, projectToken string) {
+	if projectToken == "" {
+		return
+	}
+
+	if err := pgdriver.Notify(ctx, p.db, ProjectChangesChannel, projectToken); err != nil {
+		log.Printf("Error notifying project change: %v", err)
+	}
 }
 
 func (p *ProjectData) SetFirstEventReceived(c *ctx.Ctx, projectID string) error {
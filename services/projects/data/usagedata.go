@@ -0,0 +1,70 @@
+package data
+
+import (
+	"context"
+	"time"
+	"zori/internal/storage/postgres"
+	"zori/internal/storage/postgres/models"
+
+	"github.com/uptrace/bun"
+)
+
+// UsageData stores the events organizations ingest per project and month, it is what monthly event quotas are
+// enforced against
+type UsageData struct {
+	db *bun.DB
+}
+
+func NewUsageData(db *postgres.PostgresDB) *UsageData {
+	return &UsageData{db: db.DB}
+}
+
+// AddUsage adds the events of every usage row to the ones already counted for the project and month
+func (u *UsageData) AddUsage(ctx context.Context, usage []*models.OrganizationUsage) error {
+	if len(usage) == 0 {
+		return nil
+	}
+
+	_, err := u.db.NewInsert().
+		Model(&usage).
+		On("CONFLICT (organization_id, project_id, month) DO UPDATE").
+		Set("events = ou.events + EXCLUDED.events").
+		Set("updated_at = EXCLUDED.updated_at").
+		Exec(ctx)
+	return err
+}
+
+// GetMonthlyEventQuota returns the monthly event quota of the organization, nil when it is unlimited
+func (u *UsageData) GetMonthlyEventQuota(ctx context.Context, orgID string) (*int64, error) {
+	organization := &models.Organization{}
+	err := u.db.NewSelect().
+		Model(organization).
+		Column("monthly_event_quota").
+		Where("id = ?", orgID).
+		Scan(ctx)
+	return organization.MonthlyEventQuota, err
+}
+
+// CountOrganizationEvents returns the events the organization ingested in the month across its projects
+func (u *UsageData) CountOrganizationEvents(ctx context.Context, orgID string, month time.Time) (int64, error) {
+	var events int64
+	err := u.db.NewSelect().
+		Model(&models.OrganizationUsage{}).
+		ColumnExpr("COALESCE(SUM(events), 0)").
+		Where("organization_id = ?", orgID).
+		Where("month = ?", month).
+		Scan(ctx, &events)
+	return events, err
+}
+
+// ListOrganizationUsage returns the usage of every project of the organization in the month, busiest first
+func (u *UsageData) ListOrganizationUsage(ctx context.Context, orgID string, month time.Time) ([]*models.OrganizationUsage, error) {
+	usage := []*models.OrganizationUsage{}
+	err := u.db.NewSelect().
+		Model(&usage).
+		Where("organization_id = ?", orgID).
+		Where("month = ?", month).
+		Order("events DESC").
+		Scan(ctx)
+	return usage, err
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"zori/internal/ctx"
+	"zori/internal/storage/postgres/models"
+	"zori/internal/utils"
+	"zori/services/projects/data"
+	"zori/services/projects/types"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// ListGoalsResponse represents the response for listing the goals of a project
+type ListGoalsResponse struct {
+	Goals []*models.Goal `json:"goals"`
+	Total int            `json:"total" example:"3"`
+}
+
+type GoalService struct {
+	data        *data.GoalData
+	projectData *data.ProjectData
+}
+
+func NewGoalService(data *data.GoalData, projectData *data.ProjectData) *GoalService {
+	return &GoalService{data: data, projectData: projectData}
+}
+
+// ListProjectGoals returns the goals of a project, the caller makes sure the project belongs to the organization
+func (s *GoalService) ListProjectGoals(ctx context.Context, projectID string) ([]*models.Goal, error) {
+	return s.data.ListProjectGoals(ctx, projectID)
+}
+
+// @Summary List project goals
+// @Description Get the goals defined for a project
+// @Tags Goals
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Project ID"
+// @Success 200 {object} services.ListGoalsResponse "List of goals"
+// @Failure 400 {object} map[string]interface{} "Invalid project ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/projects/{id}/goals [get]
+func (s *GoalService) ListGoals(c *ctx.Ctx) (*ListGoalsResponse, error) {
+	projectID, err := s.projectID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	goals, err := s.data.ListProjectGoals(c, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list goals: %w", err)
+	}
+
+	return &ListGoalsResponse{
+		Goals: goals,
+		Total: len(goals),
+	}, nil
+}
+
+// @Summary Create a goal
+// @Description Create a goal reached by a page view of a path, a custom event or a click on an element
+// @Tags Goals
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Project ID"
+// @Param request body types.GoalRequest true "Goal details"
+// @Success 201 {object} models.Goal "Created goal"
+// @Failure 400 {object} map[string]interface{} "Invalid request or validation failed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project not found"
+// @Failure 409 {object} map[string]interface{} "The project has too many goals"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/projects/{id}/goals [post]
+func (s *GoalService) CreateGoal(c *ctx.Ctx) (*models.Goal, error) {
+	req, err := bindGoalRequest(c)
+	if err != nil {
+		return nil, err
+	}
+
+	projectID, err := s.projectID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	goals, err := s.data.CountProjectGoals(c, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count goals: %w", err)
+	}
+	if goals >= models.MaxProjectGoals {
+		return nil, echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("A project can have at most %d goals", models.MaxProjectGoals))
+	}
+
+	goal, err := s.data.CreateGoal(c, projectID, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create goal: %w", err)
+	}
+
+	c.Echo.Response().Status = http.StatusCreated
+
+	return goal, nil
+}
+
+// @Summary Get a goal
+// @Description Get a single goal of a project by its ID
+// @Tags Goals
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Project ID"
+// @Param goal_id path string true "Goal ID"
+// @Success 200 {object} models.Goal "Goal details"
+// @Failure 400 {object} map[string]interface{} "Invalid project or goal ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project or goal not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/projects/{id}/goals/{goal_id} [get]
+func (s *GoalService) GetGoal(c *ctx.Ctx) (*models.Goal, error) {
+	projectID, err := s.projectID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	goalID, err := pathGoalID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	goal, err := s.data.GetGoal(c, projectID, goalID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Goal not found")
+		}
+		return nil, fmt.Errorf("failed to get goal: %w", err)
+	}
+
+	return goal, nil
+}
+
+// @Summary Update a goal
+// @Description Replace the name and condition of a goal
+// @Tags Goals
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Project ID"
+// @Param goal_id path string true "Goal ID"
+// @Param request body types.GoalRequest true "Goal details"
+// @Success 200 {object} models.Goal "Updated goal"
+// @Failure 400 {object} map[string]interface{} "Invalid request or validation failed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project or goal not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/projects/{id}/goals/{goal_id} [put]
+func (s *GoalService) UpdateGoal(c *ctx.Ctx) (*models.Goal, error) {
+	req, err := bindGoalRequest(c)
+	if err != nil {
+		return nil, err
+	}
+
+	projectID, err := s.projectID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	goalID, err := s.goalID(c, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	goal, err := s.data.UpdateGoal(c, projectID, goalID, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update goal: %w", err)
+	}
+
+	return goal, nil
+}
+
+// @Summary Delete a goal
+// @Description Delete a goal of a project, events are not affected
+// @Tags Goals
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Project ID"
+// @Param goal_id path string true "Goal ID"
+// @Success 200 {object} map[string]string "Deletion confirmation"
+// @Failure 400 {object} map[string]interface{} "Invalid project or goal ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]interface{} "Project or goal not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/projects/{id}/goals/{goal_id} [delete]
+func (s *GoalService) DeleteGoal(c *ctx.Ctx) (map[string]string, error) {
+	projectID, err := s.projectID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	goalID, err := s.goalID(c, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.data.DeleteGoal(c, projectID, goalID); err != nil {
+		return nil, fmt.Errorf("failed to delete goal: %w", err)
+	}
+
+	return map[string]string{
+		"message": "Goal deleted successfully",
+	}, nil
+}
+
+func bindGoalRequest(c *ctx.Ctx) (*types.GoalRequest, error) {
+	var req types.GoalRequest
+	if err := c.Echo.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if req.Match == "" {
+		req.Match = models.FunnelMatchEquals
+	}
+
+	return &req, nil
+}
+
+// projectID returns the project of the path when it belongs to the organization of the caller
+func (s *GoalService) projectID(c *ctx.Ctx) (string, error) {
+	projectID := c.Echo.Param("id")
+	if projectID == "" {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "Project ID is required")
+	}
+	parsedProjectID, err := uuid.Parse(projectID)
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "Project ID must be a valid UUID")
+	}
+	projectID = parsedProjectID.String()
+
+	exists, err := s.projectData.ProjectExists(c, projectID, c.OrgID())
+	if err != nil {
+		return "", fmt.Errorf("failed to check project existence: %w", err)
+	}
+	if !exists {
+		return "", echo.NewHTTPError(http.StatusNotFound, "Project not found")
+	}
+
+	return projectID, nil
+}
+
+// goalID returns the goal of the path when it belongs to the project
+func (s *GoalService) goalID(c *ctx.Ctx, projectID string) (string, error) {
+	goalID, err := pathGoalID(c)
+	if err != nil {
+		return "", err
+	}
+
+	exists, err := s.data.GoalExists(c, projectID, goalID)
+	if err != nil {
+		return "", fmt.Errorf("failed to check goal existence: %w", err)
+	}
+	if !exists {
+		return "", echo.NewHTTPError(http.StatusNotFound, "Goal not found")
+	}
+
+	return goalID, nil
+}
+
+// pathGoalID returns the goal ID of the path in its canonical form, IDs which are not UUIDs never reach the database
+func pathGoalID(c *ctx.Ctx) (string, error) {
+	goalID, err := uuid.Parse(c.Echo.Param("goal_id"))
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "Goal ID must be a valid UUID")
+	}
+
+	return goalID.String(), nil
+}
@@ -1,9 +1,11 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"zori/internal/ctx"
+	"zori/internal/origins"
 	"zori/internal/storage/postgres/models"
 	"zori/internal/utils"
 	"zori/services/projects/data"
@@ -35,6 +37,11 @@ func (p *ProjectService) GetProjectByPublishableToken(token string) (*models.Pro
 	return p.data.GetProjectByPublishableToken(token)
 }
 
+// GetOrganizationProject returns the project only when it belongs to the given organization
+func (p *ProjectService) GetOrganizationProject(ctx context.Context, projectID string, orgID string) (*models.Project, error) {
+	return p.data.GetProject(ctx, projectID, orgID)
+}
+
 // @Summary List organization projects
 // @Description Get a list of all projects belonging to the authenticated user's organization
 // @Tags Projects
@@ -80,6 +87,12 @@ func (s *ProjectService) CreateProject(c *ctx.Ctx) (*ProjectResponse, error) {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
+	allowedOrigins, err := normalizeAllowedOrigins(req.AllowedOrigins)
+	if err != nil {
+		return nil, err
+	}
+	req.AllowedOrigins = allowedOrigins
+
 	project, err := s.data.CreateProject(c, &req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create project: %w", err)
@@ -120,6 +133,14 @@ func (s *ProjectService) UpdateProject(c *ctx.Ctx) (*ProjectResponse, error) {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
+	if req.AllowedOrigins != nil {
+		allowedOrigins, err := normalizeAllowedOrigins(*req.AllowedOrigins)
+		if err != nil {
+			return nil, err
+		}
+		req.AllowedOrigins = &allowedOrigins
+	}
+
 	// Check if project exists
 	exists, err := s.data.ProjectExists(c.Echo.Request().Context(), projectID, c.OrgID())
 	if err != nil {
@@ -202,3 +223,13 @@ func (s *ProjectService) DeleteProject(c *ctx.Ctx) (map[string]string, error) {
 		"message": "Project deleted successfully",
 	}, nil
 }
+
+// normalizeAllowedOrigins returns the allowed origins in their canonical form, invalid patterns are a bad request
+func normalizeAllowedOrigins(allowedOrigins []string) ([]string, error) {
+	normalized, err := origins.NormalizeAll(allowedOrigins)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("allowed_origins: %s", err))
+	}
+
+	return normalized, nil
+}
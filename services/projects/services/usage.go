@@ -0,0 +1,65 @@
+package services
+
+import (
+	"fmt"
+	"time"
+	"zori/internal/ctx"
+	"zori/internal/storage/postgres/models"
+	"zori/services/projects/data"
+)
+
+// UsageResponse represents the events the organization ingested in the current month
+type UsageResponse struct {
+	Month time.Time `json:"month" example:"2024-01-01T00:00:00Z"`
+	// ResetsAt is when the next month starts and the quota is available again
+	ResetsAt time.Time `json:"resets_at" example:"2024-02-01T00:00:00Z"`
+	Events   int64     `json:"events" example:"125000"`
+	// MonthlyEventQuota is null when the organization may ingest an unlimited number of events
+	MonthlyEventQuota *int64                      `json:"monthly_event_quota" example:"1000000"`
+	Projects          []*models.OrganizationUsage `json:"projects"`
+}
+
+type UsageService struct {
+	data *data.UsageData
+}
+
+func NewUsageService(data *data.UsageData) *UsageService {
+	return &UsageService{data: data}
+}
+
+// @Summary Get organization usage
+// @Description Get the events the organization ingested in the current month (UTC) per project along with its monthly event quota.
+// @Description Usage is counted as events are accepted by the ingestion server and may lag behind by a few seconds.
+// @Tags Projects
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} services.UsageResponse "Usage of the current month"
+// @Failure 401 {object} map[string]interface{} "Unauthorized - Invalid or missing JWT token"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/v1/projects/usage [get]
+func (s *UsageService) GetUsage(c *ctx.Ctx) (*UsageResponse, error) {
+	month := models.UsageMonth(time.Now())
+
+	quota, err := s.data.GetMonthlyEventQuota(c, c.OrgID())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get monthly event quota: %w", err)
+	}
+
+	usage, err := s.data.ListOrganizationUsage(c, c.OrgID(), month)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage: %w", err)
+	}
+
+	response := &UsageResponse{
+		Month:             month,
+		ResetsAt:          month.AddDate(0, 1, 0),
+		MonthlyEventQuota: quota,
+		Projects:          usage,
+	}
+	for _, projectUsage := range usage {
+		response.Events += projectUsage.Events
+	}
+
+	return response, nil
+}
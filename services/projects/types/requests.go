@@ -4,10 +4,34 @@ type CreateProjectRequest struct {
 	Name           string `json:"name" validate:"required" example:"My Awesome Project"`
 	WebsiteURL     string `json:"website_url" validate:"required,url" example:"https://example.com"`
 	AllowLocalHost bool   `json:"allow_localhost" example:"false"`
+	// SessionTimeoutMinutes defaults to 30 minutes when not set
+	SessionTimeoutMinutes int `json:"session_timeout_minutes" validate:"omitempty,min=1,max=1440" example:"30"`
+	// Cookieless derives visitor IDs from a daily salt, the IP address and the user agent instead of a cookie
+	Cookieless bool `json:"cookieless" example:"false"`
+	// AllowedOrigins are host patterns such as *.example.com events are accepted from besides the website
+	AllowedOrigins []string `json:"allowed_origins" validate:"omitempty,max=50,dive,required,max=255" example:"*.example.com"`
+	// BotFilter tags events of bots by default, drop discards them and off disables bot detection
+	BotFilter string `json:"bot_filter" validate:"omitempty,oneof=off tag drop" example:"tag"`
 }
 
 type UpdateProjectRequest struct {
 	Name           string `json:"name" example:"Updated Project Name"`
 	WebsiteURL     string `json:"website_url" validate:"omitempty,url" example:"https://updated-example.com"`
 	AllowLocalHost bool   `json:"allow_localhost" example:"true"`
+	// SessionTimeoutMinutes is left unchanged when not set
+	SessionTimeoutMinutes *int `json:"session_timeout_minutes" validate:"omitempty,min=1,max=1440" example:"30"`
+	// Cookieless is left unchanged when not set
+	Cookieless *bool `json:"cookieless" example:"true"`
+	// AllowedOrigins replaces the allowed origins when set, an empty list removes them
+	AllowedOrigins *[]string `json:"allowed_origins" validate:"omitempty,max=50,dive,required,max=255" example:"*.example.com"`
+	// BotFilter is left unchanged when not set
+	BotFilter string `json:"bot_filter" validate:"omitempty,oneof=off tag drop" example:"drop"`
+}
+
+// GoalRequest creates or replaces a goal, Match defaults to equals
+type GoalRequest struct {
+	Name  string `json:"name" validate:"required,max=255" example:"Visited pricing"`
+	Type  string `json:"type" validate:"required,oneof=page_view event click" example:"page_view"`
+	Match string `json:"match" validate:"omitempty,oneof=equals starts_with contains pattern" example:"pattern"`
+	Value string `json:"value" validate:"required,max=1024" example:"/pricing/*"`
 }
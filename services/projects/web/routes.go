@@ -6,12 +6,14 @@ import (
 	"zori/services/projects/services"
 )
 
-func RegisterRoutes(s *server.Server, projectService *services.ProjectService, jwtMiddleware *middlewares.JwtMiddleware) {
+func RegisterRoutes(s *server.Server, projectService *services.ProjectService, goalService *services.GoalService, usageService *services.UsageService, jwtMiddleware *middlewares.JwtMiddleware) {
 	projectRouteGroup := s.Group("/api/v1/projects")
 	projectRouteGroup.Use(jwtMiddleware.Middleware())
 
 	server.GroupGET(projectRouteGroup, "/list", projectService.ListProjects)
 
+	server.GroupGET(projectRouteGroup, "/usage", usageService.GetUsage)
+
 	server.GroupGET(projectRouteGroup, "/:id", projectService.GetProject)
 
 	server.GroupPOST(projectRouteGroup, "", projectService.CreateProject)
@@ -19,4 +21,14 @@ func RegisterRoutes(s *server.Server, projectService *services.ProjectService, j
 	server.GroupPUT(projectRouteGroup, "/:id", projectService.UpdateProject)
 
 	server.GroupDELETE(projectRouteGroup, "/:id", projectService.DeleteProject)
+
+	server.GroupGET(projectRouteGroup, "/:id/goals", goalService.ListGoals)
+
+	server.GroupPOST(projectRouteGroup, "/:id/goals", goalService.CreateGoal)
+
+	server.GroupGET(projectRouteGroup, "/:id/goals/:goal_id", goalService.GetGoal)
+
+	server.GroupPUT(projectRouteGroup, "/:id/goals/:goal_id", goalService.UpdateGoal)
+
+	server.GroupDELETE(projectRouteGroup, "/:id/goals/:goal_id", goalService.DeleteGoal)
 }